@@ -0,0 +1,62 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var errNoCertificates = errors.New("no certificates found")
+
+// LoadCertPool creates a certificate pool containing the certificates from the provided PEM file.
+// Unless onlyFile is set, the certificates are added to a copy of the system pool.
+func LoadCertPool(fileName string, onlyFile bool) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !onlyFile {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("can not load system certificates: %w", err)
+		}
+		pool = systemPool
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("can not read %s: %w", fileName, errNoCertificates)
+	}
+
+	return pool, nil
+}
+
+var errNoPeerCertificates = errors.New("server did not present a certificate")
+
+// verifyCertificate checks if the certificate of the server is trusted by the roots. This also works for connections
+// which were established without verification. Uses the system pool if roots is nil.
+func verifyCertificate(state *tls.ConnectionState, roots *x509.CertPool) error {
+	if len(state.VerifiedChains) > 0 {
+		// already verified during the handshake
+		return nil
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return errNoPeerCertificates
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -1,65 +1,398 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/xperimental/nextcloud-exporter/serverinfo"
 )
 
+const (
+	headerAuthToken = "NC-Token"
+	queryAuthToken  = "token"
+)
+
 var (
 	ErrNotAuthorized = errors.New("wrong credentials")
+	// ErrTruncatedResponse is returned when the connection ended before the complete response was received.
+	ErrTruncatedResponse = errors.New("response of server is incomplete")
 )
 
-type InfoClient func() (*serverinfo.ServerInfo, error)
+// Options contains the settings used for creating an InfoClient.
+type Options struct {
+	Log            logrus.FieldLogger
+	InfoURL        string
+	Username       string
+	Password       string
+	AuthToken      string
+	AuthTokenQuery bool
+	// AuthFallbackBasic retries a request rejected when using the token with username and password.
+	AuthFallbackBasic bool
+	// BasicAuthUsername and BasicAuthPassword are sent in the Authorization header for a reverse proxy in front
+	// of the server. They are only used when the token is used for authenticating with the server.
+	BasicAuthUsername string
+	BasicAuthPassword string
+	Timeout           time.Duration
+	UserAgent         string
+	TLSSkipVerify     bool
+	// RootCAs contains the certificates used for verifying the server. Uses the system pool if nil.
+	RootCAs *x509.CertPool
+	// DNSServer is the address of a DNS server used instead of the system resolver.
+	DNSServer string
+	// Proxy is the URL of a HTTP or SOCKS5 proxy used for connecting to the server.
+	Proxy *url.URL
+	// Trace enables measuring the duration of the phases of each request.
+	Trace bool
+	// HeadPrecheck sends a HEAD request before the actual request to fail early if the server is not reachable.
+	HeadPrecheck bool
+	// MaxResponseSize is the maximum size of the response body in bytes. Zero or a negative value disables the limit.
+	MaxResponseSize int64
+	// AcceptStatusCodes contains status codes of the response which are accepted in addition to 200.
+	AcceptStatusCodes []int
+	// RetryTruncated enables requesting the server info a second time, if the first response is incomplete.
+	RetryTruncated bool
+	// RecordFile is the path of a file the raw server info is written to on every request.
+	RecordFile string
+	// RecordRedact contains the keys whose values are replaced in the recorded server info.
+	RecordRedact []string
+	// SecondaryInfoURL is the URL of a second server info endpoint. The sections contained in its response
+	// replace the sections of the response of InfoURL.
+	SecondaryInfoURL string
+}
+
+// Response contains the parsed server info together with information about the HTTP response it was read from.
+type Response struct {
+	Info   *serverinfo.ServerInfo
+	Header http.Header
+	TLS    *tls.ConnectionState
+	// CertificateError contains the result of verifying the certificate of the server. It is also set when the
+	// verification is disabled for the connection. Nil if the certificate is trusted or HTTPS is not used.
+	CertificateError error
+	// Timings is only set when tracing is enabled.
+	Timings *Timings
+	// ReceivedAt is the time the headers of the response were received.
+	ReceivedAt time.Time
+}
+
+// InfoClient requests the server info. The request is aborted once the context is cancelled.
+type InfoClient func(ctx context.Context) (*Response, error)
+
+func New(opts Options) InfoClient {
+	c := &infoClient{
+		opts:   opts,
+		client: newHTTPClient(opts),
+	}
+
+	if opts.SecondaryInfoURL != "" {
+		secondaryOpts := opts
+		secondaryOpts.InfoURL = opts.SecondaryInfoURL
+		secondaryOpts.SecondaryInfoURL = ""
+		// the server was already checked by the primary request and the recording only contains the primary response
+		secondaryOpts.HeadPrecheck = false
+		secondaryOpts.RecordFile = ""
+
+		c.secondary = &infoClient{
+			opts:   secondaryOpts,
+			client: c.client,
+		}
+	}
+
+	return c.getInfo
+}
 
-func New(infoURL, username, password, authToken string, timeout time.Duration, userAgent string, tlsSkipVerify bool) InfoClient {
-	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				// disable TLS certification verification, if desired
-				InsecureSkipVerify: tlsSkipVerify,
-			},
+func newHTTPClient(opts Options) *http.Client {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			// disable TLS certification verification, if desired
+			InsecureSkipVerify: opts.TLSSkipVerify,
+			RootCAs:            opts.RootCAs,
 		},
 	}
 
-	return func() (*serverinfo.ServerInfo, error) {
-		req, err := http.NewRequest(http.MethodGet, infoURL, nil)
-		if err != nil {
-			return nil, err
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
+	}
+
+	if opts.DNSServer != "" {
+		dialer := &net.Dialer{
+			Resolver: newResolver(opts.DNSServer),
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+	}
+}
+
+type infoClient struct {
+	opts      Options
+	client    *http.Client
+	secondary *infoClient
+}
+
+func (c *infoClient) newRequest(ctx context.Context, method string, basicAuth bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.InfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case basicAuth || c.opts.AuthToken == "":
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	case c.opts.AuthTokenQuery:
+		query := req.URL.Query()
+		query.Set(queryAuthToken, c.opts.AuthToken)
+		req.URL.RawQuery = query.Encode()
+	default:
+		req.Header.Set(headerAuthToken, c.opts.AuthToken)
+	}
+
+	if !basicAuth && c.opts.AuthToken != "" && c.opts.BasicAuthUsername != "" {
+		// the token does not use the Authorization header, so it is available for the reverse proxy
+		req.SetBasicAuth(c.opts.BasicAuthUsername, c.opts.BasicAuthPassword)
+	}
+
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+	return req, nil
+}
+
+// redactToken removes the authentication token from the URL of a request error, so that it is not logged when
+// the token is sent as query parameter.
+func (c *infoClient) redactToken(err error) error {
+	var urlErr *url.Error
+	if !c.opts.AuthTokenQuery || !errors.As(err, &urlErr) {
+		return err
+	}
+
+	parsed, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		urlErr.URL = ""
+		return err
+	}
+
+	query := parsed.Query()
+	query.Del(queryAuthToken)
+	parsed.RawQuery = query.Encode()
+	urlErr.URL = parsed.String()
+	return err
+}
+
+// canFallback returns true, if a request rejected when using the token can be retried using username and password.
+func (c *infoClient) canFallback() bool {
+	return c.opts.AuthFallbackBasic && c.opts.AuthToken != ""
+}
+
+// precheck sends a HEAD request to find out quickly if the server is reachable.
+// Servers not supporting HEAD requests are treated as reachable.
+func (c *infoClient) precheck(ctx context.Context) error {
+	req, err := c.newRequest(ctx, http.MethodHead, false)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("precheck failed: %w", &ConnectionError{Inner: c.redactToken(err)})
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil
+	case http.StatusUnauthorized:
+		if c.canFallback() {
+			// Authentication is checked again by the actual request, which can fall back to basic authentication.
+			return nil
 		}
 
-		if authToken == "" {
-			req.SetBasicAuth(username, password)
-		} else {
-			req.Header.Set("NC-Token", authToken)
+		return ErrNotAuthorized
+	default:
+		if c.acceptStatus(res.StatusCode) {
+			return nil
 		}
 
-		req.Header.Set("User-Agent", userAgent)
+		return fmt.Errorf("precheck failed: %w", &HTTPStatusError{Code: res.StatusCode})
+	}
+}
 
-		res, err := client.Do(req)
-		if err != nil {
+// acceptStatus returns true if the status code of a response is accepted.
+func (c *infoClient) acceptStatus(code int) bool {
+	if code == http.StatusOK {
+		return true
+	}
+
+	for _, accepted := range c.opts.AcceptStatusCodes {
+		if code == accepted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *infoClient) getInfo(ctx context.Context) (*Response, error) {
+	res, err := c.fetchRetry(ctx)
+	if err != nil || c.secondary == nil {
+		return res, err
+	}
+
+	secondary, err := c.secondary.fetchRetry(ctx)
+	if err != nil {
+		c.opts.Log.Warnf("Failed to get secondary server info: %s", err)
+		return res, nil
+	}
+
+	res.Info.Data.Merge(secondary.Info.Data)
+	return res, nil
+}
+
+func (c *infoClient) fetchRetry(ctx context.Context) (*Response, error) {
+	res, err := c.fetch(ctx)
+	if errors.Is(err, ErrTruncatedResponse) && c.opts.RetryTruncated {
+		c.opts.Log.Warn("Response of server was incomplete, requesting server info again.")
+		return c.fetch(ctx)
+	}
+
+	return res, err
+}
+
+func (c *infoClient) fetch(ctx context.Context) (*Response, error) {
+	if c.opts.HeadPrecheck {
+		if err := c.precheck(ctx); err != nil {
 			return nil, err
 		}
-		defer res.Body.Close()
+	}
+
+	res, tracer, err := c.get(ctx, false)
+	if err != nil {
+		return nil, err
+	}
 
-		if res.StatusCode == http.StatusUnauthorized {
-			return nil, ErrNotAuthorized
+	if res.StatusCode == http.StatusUnauthorized && c.canFallback() {
+		res.Body.Close()
+		c.opts.Log.Warn("Authentication using token failed, falling back to username and password.")
+
+		res, tracer, err = c.get(ctx, true)
+		if err != nil {
+			return nil, err
 		}
+	}
+	defer res.Body.Close()
+	receivedAt := time.Now()
+
+	var body io.Reader = res.Body
+	if c.opts.MaxResponseSize > 0 {
+		body = newLimitReader(body, c.opts.MaxResponseSize)
+	}
 
-		if res.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	accepted := res.StatusCode != http.StatusUnauthorized && c.acceptStatus(res.StatusCode)
+	if c.opts.RecordFile != "" {
+		// responses with a status code which is not accepted are recorded as well, as they help finding the problem
+		data, err := ioutil.ReadAll(body)
+		if err != nil && accepted {
+			return nil, readError(err)
 		}
 
-		status, err := serverinfo.ParseJSON(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("can not parse server info: %w", err)
+		if err := recordResponse(c.opts.Log, c.opts.RecordFile, data, c.opts.RecordRedact); err != nil {
+			c.opts.Log.Warnf("Failed to record server info: %s", err)
 		}
+		body = bytes.NewReader(data)
+	}
 
-		return status, nil
+	if res.StatusCode == http.StatusUnauthorized {
+		return nil, ErrNotAuthorized
 	}
+
+	if !accepted {
+		return nil, &HTTPStatusError{Code: res.StatusCode}
+	}
+
+	status, err := parseServerInfo(body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Response{
+		Info:       status,
+		Header:     res.Header,
+		TLS:        res.TLS,
+		ReceivedAt: receivedAt,
+	}
+	if tracer != nil {
+		result.Timings = tracer.result()
+	}
+	if res.TLS != nil {
+		result.CertificateError = verifyCertificate(res.TLS, c.opts.RootCAs)
+	}
+
+	return result, nil
+}
+
+// parseServerInfo parses the server info and classifies the errors the same way for all sources.
+func parseServerInfo(reader io.Reader) (*serverinfo.ServerInfo, error) {
+	body := &errorReader{reader: reader}
+	status, err := serverinfo.ParseJSON(body)
+	if err != nil && body.err != nil {
+		return nil, readError(body.err)
+	}
+
+	var ocsErr *serverinfo.OCSError
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// the document ended before the JSON was complete
+		return nil, ErrTruncatedResponse
+	case errors.As(err, &ocsErr):
+		return nil, ocsErr
+	case err != nil:
+		return nil, &ParseError{Inner: err}
+	}
+
+	return status, nil
+}
+
+// readError classifies an error returned while reading the server info.
+func readError(err error) error {
+	var tooLargeErr ResponseTooLargeError
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// connection was closed before the response was complete
+		return ErrTruncatedResponse
+	case errors.As(err, &tooLargeErr):
+		return tooLargeErr
+	default:
+		return &ConnectionError{Inner: fmt.Errorf("can not read server info: %w", err)}
+	}
+}
+
+func (c *infoClient) get(ctx context.Context, basicAuth bool) (*http.Response, *requestTracer, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, basicAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tracer *requestTracer
+	if c.opts.Trace {
+		tracer = &requestTracer{}
+		req = tracer.withTrace(req)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, &ConnectionError{Inner: c.redactToken(err)}
+	}
+
+	return res, tracer, nil
 }
@@ -0,0 +1,782 @@
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xperimental/nextcloud-exporter/internal/testutil"
+)
+
+func TestAcceptStatusCodes(t *testing.T) {
+	tt := []struct {
+		desc              string
+		status            int
+		acceptStatusCodes []int
+		wantErr           error
+	}{
+		{
+			desc:    "ok",
+			status:  http.StatusOK,
+			wantErr: nil,
+		},
+		{
+			desc:    "not accepted",
+			status:  http.StatusNonAuthoritativeInfo,
+			wantErr: errors.New("unexpected status code: 203"),
+		},
+		{
+			desc:              "accepted",
+			status:            http.StatusNonAuthoritativeInfo,
+			acceptStatusCodes: []int{http.StatusNonAuthoritativeInfo, http.StatusPartialContent},
+			wantErr:           nil,
+		},
+		{
+			desc:              "unauthorized",
+			status:            http.StatusUnauthorized,
+			acceptStatusCodes: []int{http.StatusNonAuthoritativeInfo},
+			wantErr:           ErrNotAuthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprintln(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+			}))
+			defer server.Close()
+
+			infoClient := New(Options{
+				Log:               logrus.New(),
+				InfoURL:           server.URL,
+				Username:          "user",
+				Password:          "password",
+				AcceptStatusCodes: tc.acceptStatusCodes,
+			})
+
+			_, err := infoClient(context.Background())
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeadPrecheck(t *testing.T) {
+	tt := []struct {
+		desc              string
+		status            int
+		acceptStatusCodes []int
+		wantErr           error
+		wantStatusCode    int
+	}{
+		{
+			desc:   "ok",
+			status: http.StatusOK,
+		},
+		{
+			desc:   "method not allowed",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			desc:   "not implemented",
+			status: http.StatusNotImplemented,
+		},
+		{
+			desc:              "accepted",
+			status:            http.StatusNonAuthoritativeInfo,
+			acceptStatusCodes: []int{http.StatusNonAuthoritativeInfo},
+		},
+		{
+			desc:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			wantErr: ErrNotAuthorized,
+		},
+		{
+			desc:           "unavailable",
+			status:         http.StatusServiceUnavailable,
+			wantErr:        errors.New("precheck failed: unexpected status code: 503"),
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var gets int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(tc.status)
+					return
+				}
+
+				atomic.AddInt32(&gets, 1)
+				fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+			}))
+			defer server.Close()
+
+			infoClient := New(Options{
+				Log:               logrus.New(),
+				InfoURL:           server.URL,
+				Username:          "user",
+				Password:          "password",
+				HeadPrecheck:      true,
+				AcceptStatusCodes: tc.acceptStatusCodes,
+			})
+
+			_, err := infoClient(context.Background())
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if tc.wantStatusCode != 0 {
+				var statusErr *HTTPStatusError
+				if !errors.As(err, &statusErr) || statusErr.Code != tc.wantStatusCode {
+					t.Errorf("got error %#v, want HTTPStatusError with code %d", err, tc.wantStatusCode)
+				}
+			}
+
+			wantGets := int32(1)
+			if tc.wantErr != nil {
+				wantGets = 0
+			}
+			if got := atomic.LoadInt32(&gets); got != wantGets {
+				t.Errorf("got %d requests after precheck, want %d", got, wantGets)
+			}
+		})
+	}
+}
+
+func TestWebDAVCheck(t *testing.T) {
+	tt := []struct {
+		desc    string
+		status  int
+		wantErr error
+	}{
+		{
+			desc:    "reachable",
+			status:  http.StatusMultiStatus,
+			wantErr: nil,
+		},
+		{
+			desc:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			wantErr: ErrNotAuthorized,
+		},
+		{
+			desc:    "not found",
+			status:  http.StatusNotFound,
+			wantErr: errors.New("unexpected status code: 404"),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != methodPropfind || r.Header.Get("Depth") != "0" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			check := NewWebDAVCheck(Options{
+				Username: "user",
+				Password: "password",
+			}, server.URL+WebDAVPath)
+
+			err := check(context.Background())
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTruncatedResponse(t *testing.T) {
+	const body = `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`
+
+	tt := []struct {
+		desc           string
+		retryTruncated bool
+		truncated      int
+		wantErr        error
+		wantRequests   int32
+	}{
+		{
+			desc:         "complete",
+			truncated:    0,
+			wantErr:      nil,
+			wantRequests: 1,
+		},
+		{
+			desc:         "truncated",
+			truncated:    1,
+			wantErr:      ErrTruncatedResponse,
+			wantRequests: 1,
+		},
+		{
+			desc:           "retry successful",
+			retryTruncated: true,
+			truncated:      1,
+			wantErr:        nil,
+			wantRequests:   2,
+		},
+		{
+			desc:           "retry truncated",
+			retryTruncated: true,
+			truncated:      2,
+			wantErr:        ErrTruncatedResponse,
+			wantRequests:   2,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count := atomic.AddInt32(&requests, 1)
+				if int(count) > tc.truncated {
+					fmt.Fprint(w, body)
+					return
+				}
+
+				// announce the complete body, but only send half of it
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				fmt.Fprint(w, body[:len(body)/2])
+			}))
+			defer server.Close()
+
+			infoClient := New(Options{
+				Log:            logrus.New(),
+				InfoURL:        server.URL,
+				Username:       "user",
+				Password:       "password",
+				RetryTruncated: tc.retryTruncated,
+			})
+
+			_, err := infoClient(context.Background())
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if got := atomic.LoadInt32(&requests); got != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestSecondaryInfo(t *testing.T) {
+	tt := []struct {
+		desc            string
+		secondaryStatus int
+		wantUsers       uint
+		wantShares      uint
+	}{
+		{
+			desc:            "merged",
+			secondaryStatus: http.StatusOK,
+			wantUsers:       5,
+			wantShares:      3,
+		},
+		{
+			desc:            "secondary failed",
+			secondaryStatus: http.StatusNotFound,
+			wantUsers:       4,
+			wantShares:      0,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/primary", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"ocs": {"data": {"nextcloud": {"storage": {"num_users": 4}}}}}`)
+			})
+			mux.HandleFunc("/secondary", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.secondaryStatus)
+				fmt.Fprint(w, `{"ocs": {"data": {"nextcloud": {"storage": {"num_users": 5}, "shares": {"num_shares": 3}}}}}`)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			infoClient := New(Options{
+				Log:              logrus.New(),
+				InfoURL:          server.URL + "/primary",
+				SecondaryInfoURL: server.URL + "/secondary",
+				Username:         "user",
+				Password:         "password",
+			})
+
+			res, err := infoClient(context.Background())
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got := res.Info.Data.Nextcloud.Storage.Users; got != tc.wantUsers {
+				t.Errorf("got %d users, want %d", got, tc.wantUsers)
+			}
+
+			if got := res.Info.Data.Nextcloud.Shares.SharesTotal; got != tc.wantShares {
+				t.Errorf("got %d shares, want %d", got, tc.wantShares)
+			}
+		})
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	t.Run("status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := New(Options{Log: logrus.New(), InfoURL: server.URL})(context.Background())
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("got error %q, want HTTPStatusError", err)
+		}
+
+		if statusErr.Code != http.StatusServiceUnavailable {
+			t.Errorf("got status code %d, want %d", statusErr.Code, http.StatusServiceUnavailable)
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<html></html>")
+		}))
+		defer server.Close()
+
+		_, err := New(Options{Log: logrus.New(), InfoURL: server.URL})(context.Background())
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("got error %q, want ParseError", err)
+		}
+	})
+
+	t.Run("connection", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		_, err := New(Options{Log: logrus.New(), InfoURL: server.URL})(context.Background())
+		var connectionErr *ConnectionError
+		if !errors.As(err, &connectionErr) {
+			t.Errorf("got error %q, want ConnectionError", err)
+		}
+	})
+	t.Run("read timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// send the beginning of the body, then stall until the client gives up
+			fmt.Fprint(w, `{"ocs": {"data": {"server": {`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		for _, recordFile := range []string{"", filepath.Join(t.TempDir(), "info.json")} {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			_, err := New(Options{
+				Log:        logrus.New(),
+				InfoURL:    server.URL,
+				RecordFile: recordFile,
+			})(ctx)
+			cancel()
+
+			var connectionErr *ConnectionError
+			if !errors.As(err, &connectionErr) || !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got error %#v with record file %q, want ConnectionError", err, recordFile)
+			}
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+		}))
+		defer server.Close()
+
+		for _, recordFile := range []string{"", filepath.Join(t.TempDir(), "info.json")} {
+			_, err := New(Options{
+				Log:             logrus.New(),
+				InfoURL:         server.URL,
+				MaxResponseSize: 10,
+				RecordFile:      recordFile,
+			})(context.Background())
+			if _, ok := err.(ResponseTooLargeError); !ok {
+				t.Errorf("got error %#v with record file %q, want ResponseTooLargeError", err, recordFile)
+			}
+		}
+	})
+}
+
+func TestReachabilityCheck(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	check, err := NewReachabilityCheck(Options{}, server.URL)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	got := check(context.Background())
+	want := map[string]bool{
+		FamilyIPv4: true,
+		FamilyIPv6: false,
+	}
+	for family, wantReachable := range want {
+		if got[family] != wantReachable {
+			t.Errorf("got reachable %v for %s, want %v", got[family], family, wantReachable)
+		}
+	}
+}
+
+func TestCertificateError(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+	}))
+	defer server.Close()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(server.Certificate())
+
+	tt := []struct {
+		desc          string
+		tlsSkipVerify bool
+		rootCAs       *x509.CertPool
+		wantErr       bool
+	}{
+		{
+			desc:    "trusted",
+			rootCAs: trusted,
+			wantErr: false,
+		},
+		{
+			desc:          "trusted without verification",
+			tlsSkipVerify: true,
+			rootCAs:       trusted,
+			wantErr:       false,
+		},
+		{
+			desc:          "untrusted without verification",
+			tlsSkipVerify: true,
+			rootCAs:       x509.NewCertPool(),
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			infoClient := New(Options{
+				Log:           logrus.New(),
+				InfoURL:       server.URL,
+				TLSSkipVerify: tc.tlsSkipVerify,
+				RootCAs:       tc.rootCAs,
+			})
+
+			res, err := infoClient(context.Background())
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if gotErr := res.CertificateError != nil; gotErr != tc.wantErr {
+				t.Errorf("got certificate error %v, want error %v", res.CertificateError, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBasicAuthWithToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "proxyuser" || password != "proxypass" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		if r.Header.Get(headerAuthToken) != "auth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+	}))
+	defer server.Close()
+
+	infoClient := New(Options{
+		Log:               logrus.New(),
+		InfoURL:           server.URL,
+		AuthToken:         "auth-token",
+		BasicAuthUsername: "proxyuser",
+		BasicAuthPassword: "proxypass",
+	})
+
+	if _, err := infoClient(context.Background()); err != nil {
+		t.Errorf("got error %q", err)
+	}
+}
+
+func TestAuthFallbackBasic(t *testing.T) {
+	tt := []struct {
+		desc         string
+		acceptToken  bool
+		acceptBasic  bool
+		fallback     bool
+		headPrecheck bool
+		wantErr      error
+		wantGets     int32
+	}{
+		{
+			desc:        "token accepted",
+			acceptToken: true,
+			acceptBasic: true,
+			fallback:    true,
+			wantGets:    1,
+		},
+		{
+			desc:        "token rejected",
+			acceptBasic: true,
+			fallback:    true,
+			wantGets:    2,
+		},
+		{
+			desc:        "fallback disabled",
+			acceptBasic: true,
+			fallback:    false,
+			wantErr:     ErrNotAuthorized,
+			wantGets:    1,
+		},
+		{
+			desc:     "both rejected",
+			fallback: true,
+			wantErr:  ErrNotAuthorized,
+			wantGets: 2,
+		},
+		{
+			desc:         "token rejected in precheck",
+			acceptBasic:  true,
+			fallback:     true,
+			headPrecheck: true,
+			wantGets:     2,
+		},
+		{
+			desc:         "token rejected in precheck with fallback disabled",
+			acceptBasic:  true,
+			fallback:     false,
+			headPrecheck: true,
+			wantErr:      ErrNotAuthorized,
+			wantGets:     0,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var gets int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					atomic.AddInt32(&gets, 1)
+				}
+
+				username, password, ok := r.BasicAuth()
+				switch {
+				case r.Header.Get(headerAuthToken) == "auth-token" && tc.acceptToken:
+				case ok && username == "user" && password == "password" && tc.acceptBasic:
+				default:
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+			}))
+			defer server.Close()
+
+			infoClient := New(Options{
+				Log:               logrus.New(),
+				InfoURL:           server.URL,
+				Username:          "user",
+				Password:          "password",
+				AuthToken:         "auth-token",
+				AuthFallbackBasic: tc.fallback,
+				HeadPrecheck:      tc.headPrecheck,
+			})
+
+			_, err := infoClient(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if got := atomic.LoadInt32(&gets); got != tc.wantGets {
+				t.Errorf("got %d requests, want %d", got, tc.wantGets)
+			}
+		})
+	}
+}
+
+func TestAuthTokenQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(queryAuthToken) != "auth-token" || r.Header.Get(headerAuthToken) != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+	}))
+	defer server.Close()
+
+	opts := Options{
+		Log:            logrus.New(),
+		InfoURL:        server.URL + "/info?format=json",
+		AuthToken:      "auth-token",
+		AuthTokenQuery: true,
+	}
+
+	if _, err := New(opts)(context.Background()); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	server.Close()
+	for _, headPrecheck := range []bool{false, true} {
+		opts.HeadPrecheck = headPrecheck
+
+		_, err := New(opts)(context.Background())
+		if err == nil {
+			t.Fatal("got no error for closed server")
+		}
+
+		if msg := err.Error(); strings.Contains(msg, "auth-token") || !strings.Contains(msg, "format=json") {
+			t.Errorf("got error %q, want error without token", msg)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	tt := []struct {
+		desc       string
+		url        string
+		trace      bool
+		wantTiming bool
+		wantTLS    bool
+	}{
+		{
+			desc:       "disabled",
+			url:        server.URL,
+			trace:      false,
+			wantTiming: false,
+		},
+		{
+			desc:       "http",
+			url:        server.URL,
+			trace:      true,
+			wantTiming: true,
+			wantTLS:    false,
+		},
+		{
+			desc:       "https",
+			url:        tlsServer.URL,
+			trace:      true,
+			wantTiming: true,
+			wantTLS:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			infoClient := New(Options{
+				Log:           logrus.New(),
+				InfoURL:       tc.url,
+				TLSSkipVerify: true,
+				Trace:         tc.trace,
+			})
+
+			res, err := infoClient(context.Background())
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !tc.wantTiming {
+				if res.Timings != nil {
+					t.Errorf("got timings %+v, want none", res.Timings)
+				}
+				return
+			}
+
+			if res.Timings == nil {
+				t.Fatal("got no timings")
+			}
+
+			if res.Timings.Connect <= 0 {
+				t.Errorf("got connect duration %s, want positive", res.Timings.Connect)
+			}
+
+			if got := res.Timings.TLSHandshake > 0; got != tc.wantTLS {
+				t.Errorf("got TLS handshake duration %s, want TLS %v", res.Timings.TLSHandshake, tc.wantTLS)
+			}
+
+			if got, want := res.Timings.FirstByte, 10*time.Millisecond; got < want {
+				t.Errorf("got first byte duration %s, want at least %s", got, want)
+			}
+		})
+	}
+}
+
+func TestReceivedAt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	res, err := New(Options{Log: logrus.New(), InfoURL: server.URL})(context.Background())
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	end := time.Now()
+
+	if res.ReceivedAt.Before(start) || end.Sub(res.ReceivedAt) < 100*time.Millisecond {
+		t.Errorf("got received time %s, want time of the headers between %s and %s", res.ReceivedAt, start, end.Add(-100*time.Millisecond))
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import "fmt"
+
+// HTTPStatusError is returned when the server responds with a status code which is not accepted.
+type HTTPStatusError struct {
+	Code int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// ParseError is returned when the response of the server can not be parsed.
+type ParseError struct {
+	Inner error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("can not parse server info: %s", e.Inner)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Inner
+}
+
+// ConnectionError is returned when the request could not be sent or the response could not be read.
+type ConnectionError struct {
+	Inner error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Inner.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Inner
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NewFile creates an InfoClient which reads the server info from a file instead of requesting it from a server.
+// This is useful for testing the exporter with captured responses.
+func NewFile(fileName string) InfoClient {
+	return func(ctx context.Context) (*Response, error) {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, &ConnectionError{Inner: fmt.Errorf("can not open server info file: %w", err)}
+		}
+		defer file.Close()
+
+		status, err := parseServerInfo(&contextReader{ctx: ctx, reader: file})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Response{
+			Info: status,
+		}, nil
+	}
+}
+
+// contextReader stops reading once the context is cancelled.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
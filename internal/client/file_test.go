@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xperimental/nextcloud-exporter/serverinfo"
+)
+
+func TestFile(t *testing.T) {
+	isParseError := func(err error) bool {
+		var parseErr *ParseError
+		return errors.As(err, &parseErr)
+	}
+
+	tt := []struct {
+		desc        string
+		content     string
+		missing     bool
+		cancel      bool
+		wantVersion string
+		wantErr     func(err error) bool
+	}{
+		{
+			desc:        "valid",
+			content:     `{"ocs": {"data": {"nextcloud": {"system": {"version": "27.1.4.2"}}}}}`,
+			wantVersion: "27.1.4.2",
+		},
+		{
+			desc:    "missing file",
+			missing: true,
+			wantErr: func(err error) bool {
+				var connectionErr *ConnectionError
+				return errors.As(err, &connectionErr) && errors.Is(err, os.ErrNotExist)
+			},
+		},
+		{
+			desc:    "invalid json",
+			content: `{"ocs": {"data": ]}}`,
+			wantErr: isParseError,
+		},
+		{
+			desc:    "truncated",
+			content: `{"ocs": {"data": {"nextcloud": {"system": {"version": "27.1.4.2"}`,
+			wantErr: func(err error) bool {
+				return errors.Is(err, ErrTruncatedResponse)
+			},
+		},
+		{
+			desc:    "ocs failure",
+			content: `{"ocs": {"meta": {"status": "failure", "statuscode": 997, "message": "Unauthorised"}}}`,
+			wantErr: func(err error) bool {
+				var ocsErr *serverinfo.OCSError
+				return errors.As(err, &ocsErr) && ocsErr.StatusCode == 997
+			},
+		},
+		{
+			desc:    "cancelled",
+			content: `{"ocs": {"data": {"nextcloud": {"system": {"version": "27.1.4.2"}}}}}`,
+			cancel:  true,
+			wantErr: func(err error) bool {
+				var connectionErr *ConnectionError
+				return errors.As(err, &connectionErr) && errors.Is(err, context.Canceled)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			fileName := filepath.Join(t.TempDir(), "info.json")
+			if !tc.missing {
+				if err := os.WriteFile(fileName, []byte(tc.content), 0o600); err != nil {
+					t.Fatalf("can not write file: %s", err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+
+			res, err := NewFile(fileName)(ctx)
+			if tc.wantErr != nil {
+				if !tc.wantErr(err) {
+					t.Errorf("got unexpected error %#v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got := res.Info.Data.Nextcloud.System.Version; got != tc.wantVersion {
+				t.Errorf("got version %q, want %q", got, tc.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"io"
+)
+
+// ResponseTooLargeError is returned when the response of the server exceeds the configured maximum size.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds maximum size of %d bytes", e.Limit)
+}
+
+// limitReader returns an error once more than limit bytes have been read from the underlying reader.
+type limitReader struct {
+	reader    io.Reader
+	limit     int64
+	remaining int64
+}
+
+func newLimitReader(reader io.Reader, limit int64) io.Reader {
+	return &limitReader{
+		reader:    reader,
+		limit:     limit,
+		remaining: limit,
+	}
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ResponseTooLargeError{Limit: r.limit}
+	}
+
+	// Read one byte more than allowed to detect responses exceeding the limit.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, ResponseTooLargeError{Limit: r.limit}
+	}
+
+	return n, err
+}
+
+// errorReader keeps the first error returned by the underlying reader, so that errors while reading the response
+// can be told apart from errors while parsing it.
+type errorReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
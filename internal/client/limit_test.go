@@ -0,0 +1,95 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLimitReader(t *testing.T) {
+	tt := []struct {
+		desc      string
+		size      int
+		limit     int64
+		oneByte   bool
+		wantBytes int
+		wantErr   bool
+	}{
+		{
+			desc:      "below limit",
+			size:      9,
+			limit:     10,
+			wantBytes: 9,
+		},
+		{
+			desc:      "exactly limit",
+			size:      10,
+			limit:     10,
+			wantBytes: 10,
+		},
+		{
+			desc:      "exactly limit with small reads",
+			size:      10,
+			limit:     10,
+			oneByte:   true,
+			wantBytes: 10,
+		},
+		{
+			desc:      "one byte over limit",
+			size:      11,
+			limit:     10,
+			wantBytes: 11,
+			wantErr:   true,
+		},
+		{
+			desc:      "one byte over limit with small reads",
+			size:      11,
+			limit:     10,
+			oneByte:   true,
+			wantBytes: 11,
+			wantErr:   true,
+		},
+		{
+			desc:      "far over limit",
+			size:      1000,
+			limit:     10,
+			wantBytes: 11,
+			wantErr:   true,
+		},
+		{
+			desc:      "empty",
+			size:      0,
+			limit:     10,
+			wantBytes: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var reader io.Reader = strings.NewReader(strings.Repeat("a", tc.size))
+			if tc.oneByte {
+				reader = iotest.OneByteReader(reader)
+			}
+
+			data, err := ioutil.ReadAll(newLimitReader(reader, tc.limit))
+			if len(data) != tc.wantBytes {
+				t.Errorf("got %d bytes, want %d", len(data), tc.wantBytes)
+			}
+
+			var tooLargeErr ResponseTooLargeError
+			if gotErr := errors.As(err, &tooLargeErr); gotErr != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+
+			if tc.wantErr && tooLargeErr.Limit != tc.limit {
+				t.Errorf("got limit %d in error, want %d", tooLargeErr.Limit, tc.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Address families checked by the ReachabilityCheck.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+var familyNetworks = map[string]struct {
+	ip  string
+	tcp string
+}{
+	FamilyIPv4: {ip: "ip4", tcp: "tcp4"},
+	FamilyIPv6: {ip: "ip6", tcp: "tcp6"},
+}
+
+// ReachabilityCheck returns for each address family, if the server accepted a connection on one of its addresses.
+type ReachabilityCheck func(ctx context.Context) map[string]bool
+
+// NewReachabilityCheck creates a check, which resolves the host of the server URL for IPv4 and IPv6 and opens a
+// TCP connection to the resolved addresses. The proxy of the options is not used.
+func NewReachabilityCheck(opts Options, serverURL string) (ReachabilityCheck, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse server URL: %w", err)
+	}
+
+	host := parsed.Hostname()
+	port := parsed.Port()
+	if port == "" {
+		port = parsed.Scheme
+	}
+
+	resolver := net.DefaultResolver
+	if opts.DNSServer != "" {
+		resolver = newResolver(opts.DNSServer)
+	}
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+	}
+
+	reachable := func(ctx context.Context, family string) bool {
+		networks := familyNetworks[family]
+		ips, err := resolver.LookupIP(ctx, networks.ip, host)
+		if err != nil {
+			return false
+		}
+
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, networks.tcp, net.JoinHostPort(ip.String(), port))
+			if err != nil {
+				continue
+			}
+			conn.Close()
+
+			return true
+		}
+
+		return false
+	}
+
+	return func(ctx context.Context) map[string]bool {
+		return map[string]bool{
+			FamilyIPv4: reachable(ctx, FamilyIPv4),
+			FamilyIPv6: reachable(ctx, FamilyIPv6),
+		}
+	}, nil
+}
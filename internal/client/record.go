@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redactedValue = "REDACTED"
+
+// recordResponse writes the raw server info to a file. The values of all keys contained in redactKeys are replaced.
+// A response which is not valid JSON is recorded unchanged, because it can not contain the redacted keys.
+func recordResponse(log logrus.FieldLogger, fileName string, data []byte, redactKeys []string) error {
+	if len(redactKeys) > 0 {
+		redacted, err := redactJSON(data, redactKeys)
+		switch {
+		case err != nil:
+			log.Warnf("Recording server info without redaction: %s", err)
+		default:
+			data = redacted
+		}
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(fileName), ".record-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), fileName)
+}
+
+func redactJSON(data []byte, redactKeys []string) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("can not parse response for redaction: %w", err)
+	}
+
+	keys := make(map[string]bool, len(redactKeys))
+	for _, key := range redactKeys {
+		keys[key] = true
+	}
+
+	return json.MarshalIndent(redactValue(document, keys), "", "  ")
+}
+
+func redactValue(value interface{}, keys map[string]bool) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if keys[key] {
+				typed[key] = redactedValue
+				continue
+			}
+
+			typed[key] = redactValue(child, keys)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = redactValue(child, keys)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"github.com/xperimental/nextcloud-exporter/internal/testutil"
+)
+
+func TestRedactJSON(t *testing.T) {
+	tt := []struct {
+		desc       string
+		input      string
+		redactKeys []string
+		wantJSON   string
+		wantErr    error
+	}{
+		{
+			desc:       "no matching keys",
+			input:      `{"ocs": {"data": {"version": "22.0.0"}}}`,
+			redactKeys: []string{"secret"},
+			wantJSON:   `{"ocs": {"data": {"version": "22.0.0"}}}`,
+		},
+		{
+			desc:       "nested keys",
+			input:      `{"ocs": {"data": {"version": "22.0.0", "database": {"type": "mysql", "size": 1234}}}}`,
+			redactKeys: []string{"version", "size"},
+			wantJSON:   `{"ocs": {"data": {"version": "REDACTED", "database": {"type": "mysql", "size": "REDACTED"}}}}`,
+		},
+		{
+			desc:       "objects in arrays",
+			input:      `{"list": [{"name": "a", "secret": "b"}]}`,
+			redactKeys: []string{"secret"},
+			wantJSON:   `{"list": [{"name": "a", "secret": "REDACTED"}]}`,
+		},
+		{
+			desc:       "invalid json",
+			input:      `{"ocs":`,
+			redactKeys: []string{"secret"},
+			wantErr:    errors.New("can not parse response for redaction: unexpected end of JSON input"),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := redactJSON([]byte(tc.input), tc.redactKeys)
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(result, &got); err != nil {
+				t.Fatalf("can not parse result: %s", err)
+			}
+			if err := json.Unmarshal([]byte(tc.wantJSON), &want); err != nil {
+				t.Fatalf("can not parse wanted JSON: %s", err)
+			}
+
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("result differs: -got +want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecordResponse(t *testing.T) {
+	tt := []struct {
+		desc       string
+		status     int
+		body       string
+		wantRecord string
+		wantErr    error
+	}{
+		{
+			desc:       "redacted",
+			status:     http.StatusOK,
+			body:       `{"secret": "value"}`,
+			wantRecord: "{\n  \"secret\": \"REDACTED\"\n}",
+			wantErr:    nil,
+		},
+		{
+			desc:       "not json",
+			status:     http.StatusOK,
+			body:       "<html>Maintenance</html>",
+			wantRecord: "<html>Maintenance</html>",
+			wantErr:    errors.New("can not parse server info: invalid character '<' looking for beginning of value"),
+		},
+		{
+			desc:       "status not accepted",
+			status:     http.StatusServiceUnavailable,
+			body:       "<html>Service Unavailable</html>",
+			wantRecord: "<html>Service Unavailable</html>",
+			wantErr:    errors.New("unexpected status code: 503"),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			recordFile := filepath.Join(t.TempDir(), "info.json")
+			_, err := New(Options{
+				Log:          logrus.New(),
+				InfoURL:      server.URL,
+				RecordFile:   recordFile,
+				RecordRedact: []string{"secret"},
+			})(context.Background())
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			data, err := ioutil.ReadFile(recordFile)
+			if err != nil {
+				t.Fatalf("can not read recorded file: %s", err)
+			}
+
+			if got := string(data); got != tc.wantRecord {
+				t.Errorf("got record %q, want %q", got, tc.wantRecord)
+			}
+		})
+	}
+}
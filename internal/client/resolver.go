@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"net"
+)
+
+const defaultDNSPort = "53"
+
+// newResolver creates a resolver sending all DNS queries to the provided server.
+// The default DNS port is used, if the address does not contain a port.
+func newResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultDNSPort)
+	}
+
+	dialer := &net.Dialer{}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
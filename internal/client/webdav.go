@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// WebDAVPath is the path of the WebDAV endpoint used by the sync clients.
+	WebDAVPath = "/remote.php/dav/"
+
+	methodPropfind = "PROPFIND"
+)
+
+// WebDAVCheck checks if the WebDAV endpoint of the server answers authenticated requests.
+type WebDAVCheck func(ctx context.Context) error
+
+// NewWebDAVCheck creates a check, which requests the properties of the WebDAV root using username and password.
+// The InfoURL of the options is not used.
+func NewWebDAVCheck(opts Options, davURL string) WebDAVCheck {
+	client := newHTTPClient(opts)
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, methodPropfind, davURL, nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(opts.Username, opts.Password)
+		req.Header.Set("Depth", "0")
+		req.Header.Set("User-Agent", opts.UserAgent)
+
+		res, err := client.Do(req)
+		if err != nil {
+			return &ConnectionError{Inner: err}
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+
+		switch res.StatusCode {
+		case http.StatusMultiStatus:
+			return nil
+		case http.StatusUnauthorized:
+			return ErrNotAuthorized
+		default:
+			return &HTTPStatusError{Code: res.StatusCode}
+		}
+	}
+}
@@ -4,26 +4,73 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/xperimental/nextcloud-exporter/serverinfo"
 	"gopkg.in/yaml.v2"
 )
 
 const (
-	envPrefix        = "NEXTCLOUD_"
-	envListenAddress = envPrefix + "LISTEN_ADDRESS"
-	envTimeout       = envPrefix + "TIMEOUT"
-	envServerURL     = envPrefix + "SERVER"
-	envUsername      = envPrefix + "USERNAME"
-	envPassword      = envPrefix + "PASSWORD"
-	envAuthToken     = envPrefix + "AUTH_TOKEN"
-	envTLSSkipVerify = envPrefix + "TLS_SKIP_VERIFY"
+	envPrefix                  = "NEXTCLOUD_"
+	envListenAddress           = envPrefix + "LISTEN_ADDRESS"
+	envWebConfigFile           = envPrefix + "WEB_CONFIG_FILE"
+	envWebRoutePrefix          = envPrefix + "WEB_ROUTE_PREFIX"
+	envTimeout                 = envPrefix + "TIMEOUT"
+	envCollectTimeout          = envPrefix + "COLLECT_TIMEOUT"
+	envScrapeInterval          = envPrefix + "SCRAPE_INTERVAL"
+	envServerURL               = envPrefix + "SERVER"
+	envUsername                = envPrefix + "USERNAME"
+	envPassword                = envPrefix + "PASSWORD"
+	envAuthToken               = envPrefix + "AUTH_TOKEN"
+	envAuthTokenQuery          = envPrefix + "AUTH_TOKEN_QUERY"
+	envAuthFallbackBasic       = envPrefix + "AUTH_FALLBACK_BASIC"
+	envBasicAuthUsername       = envPrefix + "BASIC_AUTH_USERNAME"
+	envBasicAuthPassword       = envPrefix + "BASIC_AUTH_PASSWORD"
+	envTLSSkipVerify           = envPrefix + "TLS_SKIP_VERIFY"
+	envTLSCAFile               = envPrefix + "TLS_CA_FILE"
+	envTLSCAOnly               = envPrefix + "TLS_CA_ONLY"
+	envDNSServer               = envPrefix + "DNS_SERVER"
+	envProxy                   = envPrefix + "PROXY"
+	envCircuitBreakerThreshold = envPrefix + "CIRCUIT_BREAKER_THRESHOLD"
+	envCircuitBreakerCooldown  = envPrefix + "CIRCUIT_BREAKER_COOLDOWN"
+	envMetricsPrefix           = envPrefix + "METRICS_PREFIX"
+	envMajorVersionLabel       = envPrefix + "MAJOR_VERSION_LABEL"
+	envCompatMetrics           = envPrefix + "COMPAT_METRICS"
+	envPHPEndOfLife            = envPrefix + "PHP_EOL"
+	envPHPSettings             = envPrefix + "PHP_SETTINGS"
+	envScrapeDurationBuckets   = envPrefix + "SCRAPE_DURATION_BUCKETS"
+	envHTTPTrace               = envPrefix + "HTTP_TRACE"
+	envHeadPrecheck            = envPrefix + "HEAD_PRECHECK"
+	envWebDAVCheck             = envPrefix + "WEBDAV_CHECK"
+	envReachabilityCheck       = envPrefix + "REACHABILITY_CHECK"
+	envLabels                  = envPrefix + "LABELS"
+	envMaxResponseSize         = envPrefix + "MAX_RESPONSE_SIZE"
+	envAPIVersion              = envPrefix + "API_VERSION"
+	envMinVersion              = envPrefix + "MIN_VERSION"
+	envMinVersionExit          = envPrefix + "MIN_VERSION_EXIT"
+	envSecondaryInfoPath       = envPrefix + "SECONDARY_INFO_PATH"
+	envRecordFile              = envPrefix + "RECORD_FILE"
+	envRecordRedact            = envPrefix + "RECORD_REDACT"
+	envAcceptStatusCodes       = envPrefix + "ACCEPT_STATUS_CODES"
+	envRetryTruncated          = envPrefix + "RETRY_TRUNCATED"
+	envOutputFile              = envPrefix + "OUTPUT_FILE"
+	envPushURL                 = envPrefix + "PUSH_URL"
+	envPushJob                 = envPrefix + "PUSH_JOB"
+	envPushInterval            = envPrefix + "PUSH_INTERVAL"
+	envLeaderLockFile          = envPrefix + "LEADER_LOCK_FILE"
+	envLeaderLeaseDuration     = envPrefix + "LEADER_LEASE_DURATION"
+	envPushLabels              = envPrefix + "PUSH_LABELS"
 )
 
+// fileURLPrefix marks a server URL pointing to a local file containing the server info.
+const fileURLPrefix = "file://"
+
 // RunMode signals what the main application should do after parsing the options.
 type RunMode int
 
@@ -36,6 +83,10 @@ const (
 	RunModeLogin
 	// RunModeVersion shows version information.
 	RunModeVersion
+	// RunModeCheck requests the server info once to check the configuration.
+	RunModeCheck
+	// RunModeOnce collects the metrics once and writes them to a file or stdout.
+	RunModeOnce
 )
 
 func (m RunMode) String() string {
@@ -48,6 +99,10 @@ func (m RunMode) String() string {
 		return "login"
 	case RunModeVersion:
 		return "version"
+	case RunModeCheck:
+		return "check"
+	case RunModeOnce:
+		return "once"
 	default:
 		return "error"
 	}
@@ -55,29 +110,141 @@ func (m RunMode) String() string {
 
 // Config contains the configuration options for nextcloud-exporter.
 type Config struct {
-	ListenAddr    string        `yaml:"listenAddress"`
-	Timeout       time.Duration `yaml:"timeout"`
-	ServerURL     string        `yaml:"server"`
-	Username      string        `yaml:"username"`
-	Password      string        `yaml:"password"`
-	AuthToken     string        `yaml:"authToken"`
-	TLSSkipVerify bool          `yaml:"tlsSkipVerify"`
-	RunMode       RunMode
+	ListenAddr              string            `yaml:"listenAddress"`
+	WebConfigFile           string            `yaml:"webConfigFile"`
+	WebRoutePrefix          string            `yaml:"webRoutePrefix"`
+	Timeout                 time.Duration     `yaml:"timeout"`
+	CollectTimeout          time.Duration     `yaml:"collectTimeout"`
+	ScrapeInterval          time.Duration     `yaml:"scrapeInterval"`
+	ServerURL               string            `yaml:"server"`
+	Username                string            `yaml:"username"`
+	Password                string            `yaml:"password"`
+	AuthToken               string            `yaml:"authToken"`
+	AuthTokenQuery          bool              `yaml:"authTokenQuery"`
+	AuthFallbackBasic       bool              `yaml:"authFallbackBasic"`
+	BasicAuthUsername       string            `yaml:"basicAuthUsername"`
+	BasicAuthPassword       string            `yaml:"basicAuthPassword"`
+	TLSSkipVerify           bool              `yaml:"tlsSkipVerify"`
+	TLSCAFile               string            `yaml:"tlsCaFile"`
+	TLSCAOnly               bool              `yaml:"tlsCaOnly"`
+	DNSServer               string            `yaml:"dnsServer"`
+	Proxy                   string            `yaml:"proxy"`
+	CircuitBreakerThreshold int               `yaml:"circuitBreakerThreshold"`
+	CircuitBreakerCooldown  time.Duration     `yaml:"circuitBreakerCooldown"`
+	MetricsPrefix           string            `yaml:"metricsPrefix"`
+	MajorVersionLabel       bool              `yaml:"majorVersionLabel"`
+	CompatMetrics           bool              `yaml:"compatMetrics"`
+	PHPEndOfLife            map[string]string `yaml:"phpEndOfLife"`
+	PHPSettings             []string          `yaml:"phpSettings"`
+	ScrapeDurationBuckets   []float64         `yaml:"scrapeDurationBuckets"`
+	HTTPTrace               bool              `yaml:"httpTrace"`
+	HeadPrecheck            bool              `yaml:"headPrecheck"`
+	WebDAVCheck             bool              `yaml:"webdavCheck"`
+	ReachabilityCheck       bool              `yaml:"reachabilityCheck"`
+	Labels                  map[string]string `yaml:"labels"`
+	MaxResponseSize         int64             `yaml:"maxResponseSize"`
+	AcceptStatusCodes       []int             `yaml:"acceptStatusCodes"`
+	RetryTruncated          bool              `yaml:"retryTruncated"`
+	APIVersion              string            `yaml:"apiVersion"`
+	MinVersion              string            `yaml:"minVersion"`
+	MinVersionExit          bool              `yaml:"minVersionExit"`
+	SecondaryInfoPath       string            `yaml:"secondaryInfoPath"`
+	RecordFile              string            `yaml:"recordFile"`
+	RecordRedact            []string          `yaml:"recordRedact"`
+	OutputFile              string            `yaml:"outputFile"`
+	PushURL                 string            `yaml:"pushUrl"`
+	PushJob                 string            `yaml:"pushJob"`
+	PushInterval            time.Duration     `yaml:"pushInterval"`
+	PushLabels              map[string]string `yaml:"pushLabels"`
+	LeaderLockFile          string            `yaml:"leaderLockFile"`
+	LeaderLeaseDuration     time.Duration     `yaml:"leaderLeaseDuration"`
+	RunMode                 RunMode
 }
 
 var (
-	errValidateNoServerURL = errors.New("need to set a server URL")
-	errValidateNoAuth      = errors.New("need to either set username/password or a token")
-	errValidateNoUsername  = errors.New("need to provide a username")
-	errValidateNoPassword  = errors.New("need to provide a password")
+	errValidateNoServerURL       = errors.New("need to set a server URL")
+	errValidateNoAuth            = errors.New("need to either set username/password or a token")
+	errValidateNoUsername        = errors.New("need to provide a username")
+	errValidateNoPassword        = errors.New("need to provide a password")
+	errValidateNoFallback        = errors.New("need to provide username and password for falling back to basic authentication")
+	errValidateBasicAuth         = errors.New("need to provide username and password for the basic authentication")
+	errValidateBasicAuthNoToken  = errors.New("basic authentication needs an authentication token for Nextcloud and can not be combined with the fallback to username and password or the WebDAV check")
+	errValidatePHPSettings       = errors.New("PHP settings must not contain a name twice")
+	errValidateWebDAVNoAuth      = errors.New("need to provide username and password for the WebDAV check")
+	errValidateNoCAFile          = errors.New("need to provide a CA file when using only the provided CA")
+	errValidateAPIVersion        = errors.New("API version needs to look like \"v1\"")
+	errValidateMinVersion        = errors.New("minimum version needs to look like \"27.1.4\"")
+	errValidateSecondaryInfoPath = errors.New("secondary info path needs to start with \"/\"")
+	errValidatePushInterval      = errors.New("push interval needs to be positive")
+	errValidateLeaderLease       = errors.New("leader lease duration needs to be positive")
+	errValidateProxy             = errors.New("proxy needs to be a URL using http, https or socks5")
+	errValidateStatusCode        = errors.New("accepted status codes need to be between 100 and 599")
+
+	apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
 )
 
+// InfoFile returns the path of the file containing the server info, if the server URL uses the "file://" scheme.
+// Otherwise an empty string is returned.
+func (c Config) InfoFile() string {
+	if !strings.HasPrefix(c.ServerURL, fileURLPrefix) {
+		return ""
+	}
+
+	return strings.TrimPrefix(c.ServerURL, fileURLPrefix)
+}
+
+// RoutePrefix returns the path prefix of the HTTP endpoints with a leading and without a trailing slash.
+// An empty string is returned if no prefix is configured.
+func (c Config) RoutePrefix() string {
+	prefix := strings.Trim(c.WebRoutePrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+
+	return "/" + prefix
+}
+
 // Validate checks if the configuration contains all necessary parameters.
 func (c Config) Validate() error {
 	if len(c.ServerURL) == 0 {
 		return errValidateNoServerURL
 	}
 
+	if c.PushURL != "" && c.PushInterval <= 0 {
+		return errValidatePushInterval
+	}
+
+	if c.LeaderLockFile != "" && c.LeaderLeaseDuration <= 0 {
+		return errValidateLeaderLease
+	}
+
+	if c.MinVersion != "" {
+		if _, err := serverinfo.ParseVersion(c.MinVersion); err != nil {
+			return errValidateMinVersion
+		}
+	}
+
+	seenSettings := make(map[string]bool)
+	for _, name := range c.PHPSettings {
+		if seenSettings[name] {
+			return errValidatePHPSettings
+		}
+		seenSettings[name] = true
+	}
+
+	// the remaining options only apply to requests to the server
+	if c.InfoFile() != "" {
+		return nil
+	}
+
+	if c.APIVersion != "" && !apiVersionPattern.MatchString(c.APIVersion) {
+		return errValidateAPIVersion
+	}
+
+	if c.SecondaryInfoPath != "" && !strings.HasPrefix(c.SecondaryInfoPath, "/") {
+		return errValidateSecondaryInfoPath
+	}
+
 	if len(c.AuthToken) == 0 {
 		if len(c.Username) == 0 && len(c.Password) == 0 {
 			return errValidateNoAuth
@@ -92,9 +259,55 @@ func (c Config) Validate() error {
 		}
 	}
 
+	if c.TLSCAOnly && len(c.TLSCAFile) == 0 {
+		return errValidateNoCAFile
+	}
+
+	if c.AuthFallbackBasic && (len(c.Username) == 0 || len(c.Password) == 0) {
+		return errValidateNoFallback
+	}
+
+	if c.WebDAVCheck && (len(c.Username) == 0 || len(c.Password) == 0) {
+		return errValidateWebDAVNoAuth
+	}
+
+	if len(c.BasicAuthUsername) > 0 || len(c.BasicAuthPassword) > 0 {
+		if len(c.BasicAuthUsername) == 0 || len(c.BasicAuthPassword) == 0 {
+			return errValidateBasicAuth
+		}
+
+		if len(c.AuthToken) == 0 || c.AuthFallbackBasic || c.WebDAVCheck {
+			return errValidateBasicAuthNoToken
+		}
+	}
+
+	if c.Proxy != "" && !validProxy(c.Proxy) {
+		return errValidateProxy
+	}
+
+	for _, code := range c.AcceptStatusCodes {
+		if code < 100 || code > 599 {
+			return errValidateStatusCode
+		}
+	}
+
 	return nil
 }
 
+func validProxy(proxy string) bool {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u.Host != ""
+	default:
+		return false
+	}
+}
+
 // Get loads the configuration. Flags, environment variables and configuration file are considered.
 func Get() (Config, error) {
 	return parseConfig(os.Args, os.Getenv)
@@ -141,13 +354,29 @@ func parseConfig(args []string, envFunc func(string) string) (Config, error) {
 		result.AuthToken = authToken
 	}
 
+	if strings.HasPrefix(result.BasicAuthPassword, "@") {
+		fileName := strings.TrimPrefix(result.BasicAuthPassword, "@")
+		password, err := readPasswordFile(fileName)
+		if err != nil {
+			return Config{}, fmt.Errorf("can not read basic authentication password file: %w", err)
+		}
+
+		result.BasicAuthPassword = password
+	}
+
 	return result, nil
 }
 
 func defaultConfig() Config {
 	return Config{
-		ListenAddr: ":9205",
-		Timeout:    5 * time.Second,
+		ListenAddr:             ":9205",
+		Timeout:                5 * time.Second,
+		CircuitBreakerCooldown: time.Minute,
+		MetricsPrefix:          "nextcloud_",
+		APIVersion:             serverinfo.DefaultAPIVersion,
+		PushJob:                "nextcloud",
+		PushInterval:           time.Minute,
+		LeaderLeaseDuration:    15 * time.Second,
 	}
 }
 
@@ -157,14 +386,57 @@ func loadConfigFromFlags(args []string) (result Config, configFile string, err e
 	flags := pflag.NewFlagSet(args[0], pflag.ContinueOnError)
 	flags.StringVarP(&configFile, "config-file", "c", "", "Path to YAML configuration file.")
 	flags.StringVarP(&result.ListenAddr, "addr", "a", defaults.ListenAddr, "Address to listen on for connections.")
+	flags.StringVar(&result.WebConfigFile, "web.config.file", defaults.WebConfigFile, "Path to configuration file that can enable TLS or authentication for the metrics endpoint.")
+	flags.StringVar(&result.WebRoutePrefix, "web.route-prefix", defaults.WebRoutePrefix, "Path prefix of the HTTP endpoints, for example when the exporter is served below a sub-path by a reverse proxy.")
 	flags.DurationVarP(&result.Timeout, "timeout", "t", defaults.Timeout, "Timeout for getting server info document.")
+	flags.DurationVar(&result.CollectTimeout, "collect-timeout", defaults.CollectTimeout, "Timeout for requesting and parsing the server info during a scrape. Zero disables the timeout.")
+	flags.DurationVar(&result.ScrapeInterval, "scrape-interval", defaults.ScrapeInterval, "Interval for querying the server in the background. The metrics endpoint returns the result of the last query. Zero queries the server on every scrape.")
 	flags.StringVarP(&result.ServerURL, "server", "s", "", "URL to Nextcloud server.")
 	flags.StringVarP(&result.Username, "username", "u", defaults.Username, "Username for connecting to Nextcloud.")
 	flags.StringVarP(&result.Password, "password", "p", defaults.Password, "Password for connecting to Nextcloud.")
 	flags.StringVar(&result.AuthToken, "auth-token", defaults.AuthToken, "Authentication token. Can replace username and password when using Nextcloud 22 or newer.")
+	flags.BoolVar(&result.AuthTokenQuery, "auth-token-query", defaults.AuthTokenQuery, "Send authentication token as query parameter instead of header.")
+	flags.BoolVar(&result.AuthFallbackBasic, "auth-fallback-basic", defaults.AuthFallbackBasic, "Retry using username and password if the server rejects the authentication token.")
+	flags.StringVar(&result.BasicAuthUsername, "basic-auth-username", defaults.BasicAuthUsername, "Username for the basic authentication of a reverse proxy in front of Nextcloud. Needs an authentication token for Nextcloud.")
+	flags.StringVar(&result.BasicAuthPassword, "basic-auth-password", defaults.BasicAuthPassword, "Password for the basic authentication of a reverse proxy in front of Nextcloud.")
 	flags.BoolVar(&result.TLSSkipVerify, "tls-skip-verify", defaults.TLSSkipVerify, "Skip certificate verification of Nextcloud server.")
+	flags.StringVar(&result.TLSCAFile, "tls-ca-file", defaults.TLSCAFile, "Path to PEM file with additional CA certificates used for verifying the Nextcloud server.")
+	flags.BoolVar(&result.TLSCAOnly, "tls-ca-only", defaults.TLSCAOnly, "Only use the certificates from the CA file instead of adding them to the system certificates.")
+	flags.StringVar(&result.DNSServer, "dns-server", defaults.DNSServer, "Address (host or host:port) of a DNS server used for resolving the Nextcloud hostname instead of the system resolver.")
+	flags.StringVar(&result.Proxy, "proxy", defaults.Proxy, "URL of HTTP or SOCKS5 proxy used for connecting to Nextcloud (for example socks5://localhost:1080).")
+	flags.IntVar(&result.CircuitBreakerThreshold, "circuit-breaker-threshold", defaults.CircuitBreakerThreshold, "Number of consecutive failed scrapes after which the server is not queried for the cooldown period. Zero disables the circuit breaker.")
+	flags.DurationVar(&result.CircuitBreakerCooldown, "circuit-breaker-cooldown", defaults.CircuitBreakerCooldown, "Time for which the server is not queried once the circuit breaker is open.")
+	flags.StringVar(&result.MetricsPrefix, "metrics-prefix", defaults.MetricsPrefix, "Prefix used for the names of all exported metrics.")
+	flags.BoolVar(&result.MajorVersionLabel, "major-version-label", defaults.MajorVersionLabel, "Add the major version of Nextcloud as label \"major_version\" to the metrics about the server.")
+	flags.BoolVar(&result.CompatMetrics, "compat-metrics", defaults.CompatMetrics, "Also export deprecated metrics, which have been replaced in earlier versions.")
+	flags.StringToStringVar(&result.PHPEndOfLife, "php-eol", defaults.PHPEndOfLife, "End-of-life dates of PHP versions, overriding the built-in list (for example 8.1=2025-12-31).")
+	flags.StringSliceVar(&result.PHPSettings, "php-settings", defaults.PHPSettings, "Keys of the PHP section of the server info exported as settings (for example max_execution_time,output_buffering).")
+	flags.Float64SliceVar(&result.ScrapeDurationBuckets, "scrape-duration-buckets", defaults.ScrapeDurationBuckets, "Buckets in seconds of the scrape duration histogram. Uses the Prometheus default buckets if not set.")
+	flags.BoolVar(&result.HTTPTrace, "http-trace", defaults.HTTPTrace, "Export the duration of the phases (DNS, connect, TLS, first byte) of the request to Nextcloud.")
+	flags.BoolVar(&result.HeadPrecheck, "head-precheck", defaults.HeadPrecheck, "Send a HEAD request before requesting the server info to detect unreachable servers early.")
+	flags.BoolVar(&result.WebDAVCheck, "webdav-check", defaults.WebDAVCheck, "Check on every scrape that the WebDAV endpoint answers authenticated requests. Needs username and password.")
+	flags.BoolVar(&result.ReachabilityCheck, "reachability-check", defaults.ReachabilityCheck, "Check on every scrape that the server accepts connections using IPv4 and IPv6.")
+	flags.StringToStringVar(&result.Labels, "label", defaults.Labels, "Static labels added to all exported metrics (for example environment=prod). Can be repeated.")
+	flags.StringVar(&result.APIVersion, "api-version", defaults.APIVersion, "Version of the serverinfo API used in the request path.")
+	flags.StringVar(&result.MinVersion, "min-version", defaults.MinVersion, "Minimum version of Nextcloud. An older server is reported as an error on startup or after collecting the metrics with --once and fails the check mode.")
+	flags.BoolVar(&result.MinVersionExit, "min-version-exit", defaults.MinVersionExit, "Exit on startup, or with an error after collecting the metrics with --once, if the server is older than the minimum version.")
+	flags.StringVar(&result.SecondaryInfoPath, "secondary-info-path", defaults.SecondaryInfoPath, "Path of a second server info endpoint. Sections contained in its response replace the sections of the main endpoint.")
+	flags.Int64Var(&result.MaxResponseSize, "max-response-size", defaults.MaxResponseSize, "Maximum size in bytes of the server info response. Zero or a negative value disables the limit.")
+	flags.IntSliceVar(&result.AcceptStatusCodes, "accept-status-codes", defaults.AcceptStatusCodes, "Additional HTTP status codes of the server info response which are accepted besides 200 (for example 203).")
+	flags.BoolVar(&result.RetryTruncated, "retry-truncated", defaults.RetryTruncated, "Request the server info a second time, if the connection ended before the complete response was received.")
+	flags.StringVar(&result.RecordFile, "record-file", defaults.RecordFile, "Path to file the raw server info is written to on every scrape.")
+	flags.StringSliceVar(&result.RecordRedact, "record-redact", defaults.RecordRedact, "Keys whose values are replaced in the recorded server info.")
+	flags.StringVar(&result.OutputFile, "output-file", defaults.OutputFile, "File the metrics are written to when using --once. Uses stdout if not set.")
+	flags.StringVar(&result.PushURL, "push-url", defaults.PushURL, "URL of Pushgateway the metrics are pushed to. Pushing is disabled if not set.")
+	flags.StringVar(&result.PushJob, "push-job", defaults.PushJob, "Job name used when pushing metrics.")
+	flags.DurationVar(&result.PushInterval, "push-interval", defaults.PushInterval, "Interval for pushing metrics.")
+	flags.StringToStringVar(&result.PushLabels, "push-label", defaults.PushLabels, "Grouping labels used when pushing metrics (for example instance=cloud1). Can be repeated.")
+	flags.StringVar(&result.LeaderLockFile, "leader-lock-file", defaults.LeaderLockFile, "Lock file on shared storage used for electing a leader between multiple instances. Only the leader queries the server.")
+	flags.DurationVar(&result.LeaderLeaseDuration, "leader-lease-duration", defaults.LeaderLeaseDuration, "Duration after which another instance takes over, if the leader did not renew the lease.")
 	modeLogin := flags.Bool("login", false, "Use interactive login to create app password.")
 	modeVersion := flags.BoolP("version", "V", false, "Show version information and exit.")
+	modeCheck := flags.Bool("check", false, "Check configuration by requesting server info once and exit.")
+	modeOnce := flags.Bool("once", false, "Collect metrics once, write them to stdout or the output file and exit.")
 
 	if err := flags.Parse(args[1:]); err != nil {
 		if err == pflag.ErrHelp {
@@ -186,6 +458,14 @@ func loadConfigFromFlags(args []string) (result Config, configFile string, err e
 		result.RunMode = RunModeLogin
 	}
 
+	if *modeCheck {
+		result.RunMode = RunModeCheck
+	}
+
+	if *modeOnce {
+		result.RunMode = RunModeOnce
+	}
+
 	return result, configFile, nil
 }
 
@@ -204,22 +484,100 @@ func loadConfigFromFile(fileName string) (Config, error) {
 }
 
 func loadConfigFromEnv(getEnv func(string) string) (Config, error) {
-	tlsSkipVerify := false
-	if rawValue := getEnv(envTLSSkipVerify); rawValue != "" {
-		value, err := strconv.ParseBool(rawValue)
-		if err != nil {
-			return Config{}, fmt.Errorf("can not parse value for %q: %s", envTLSSkipVerify, rawValue)
-		}
-		tlsSkipVerify = value
+	tlsSkipVerify, err := parseEnvBool(getEnv, envTLSSkipVerify)
+	if err != nil {
+		return Config{}, err
+	}
+
+	authTokenQuery, err := parseEnvBool(getEnv, envAuthTokenQuery)
+	if err != nil {
+		return Config{}, err
+	}
+
+	tlsCAOnly, err := parseEnvBool(getEnv, envTLSCAOnly)
+	if err != nil {
+		return Config{}, err
+	}
+
+	authFallbackBasic, err := parseEnvBool(getEnv, envAuthFallbackBasic)
+	if err != nil {
+		return Config{}, err
+	}
+
+	httpTrace, err := parseEnvBool(getEnv, envHTTPTrace)
+	if err != nil {
+		return Config{}, err
+	}
+
+	headPrecheck, err := parseEnvBool(getEnv, envHeadPrecheck)
+	if err != nil {
+		return Config{}, err
+	}
+
+	webdavCheck, err := parseEnvBool(getEnv, envWebDAVCheck)
+	if err != nil {
+		return Config{}, err
+	}
+
+	reachabilityCheck, err := parseEnvBool(getEnv, envReachabilityCheck)
+	if err != nil {
+		return Config{}, err
+	}
+
+	retryTruncated, err := parseEnvBool(getEnv, envRetryTruncated)
+	if err != nil {
+		return Config{}, err
+	}
+
+	minVersionExit, err := parseEnvBool(getEnv, envMinVersionExit)
+	if err != nil {
+		return Config{}, err
+	}
+
+	majorVersionLabel, err := parseEnvBool(getEnv, envMajorVersionLabel)
+	if err != nil {
+		return Config{}, err
+	}
+
+	compatMetrics, err := parseEnvBool(getEnv, envCompatMetrics)
+	if err != nil {
+		return Config{}, err
 	}
 
 	result := Config{
-		ListenAddr:    getEnv(envListenAddress),
-		ServerURL:     getEnv(envServerURL),
-		Username:      getEnv(envUsername),
-		Password:      getEnv(envPassword),
-		AuthToken:     getEnv(envAuthToken),
-		TLSSkipVerify: tlsSkipVerify,
+		ListenAddr:        getEnv(envListenAddress),
+		WebConfigFile:     getEnv(envWebConfigFile),
+		WebRoutePrefix:    getEnv(envWebRoutePrefix),
+		ServerURL:         getEnv(envServerURL),
+		Username:          getEnv(envUsername),
+		Password:          getEnv(envPassword),
+		AuthToken:         getEnv(envAuthToken),
+		MetricsPrefix:     getEnv(envMetricsPrefix),
+		APIVersion:        getEnv(envAPIVersion),
+		MinVersion:        getEnv(envMinVersion),
+		MinVersionExit:    minVersionExit,
+		SecondaryInfoPath: getEnv(envSecondaryInfoPath),
+		DNSServer:         getEnv(envDNSServer),
+		Proxy:             getEnv(envProxy),
+		TLSCAFile:         getEnv(envTLSCAFile),
+		RecordFile:        getEnv(envRecordFile),
+		OutputFile:        getEnv(envOutputFile),
+		PushURL:           getEnv(envPushURL),
+		PushJob:           getEnv(envPushJob),
+		LeaderLockFile:    getEnv(envLeaderLockFile),
+		AuthTokenQuery:    authTokenQuery,
+		AuthFallbackBasic: authFallbackBasic,
+		BasicAuthUsername: getEnv(envBasicAuthUsername),
+		BasicAuthPassword: getEnv(envBasicAuthPassword),
+		TLSSkipVerify:     tlsSkipVerify,
+		TLSCAOnly:         tlsCAOnly,
+		HTTPTrace:         httpTrace,
+		HeadPrecheck:      headPrecheck,
+		WebDAVCheck:       webdavCheck,
+		ReachabilityCheck: reachabilityCheck,
+		RetryTruncated:    retryTruncated,
+		MajorVersionLabel: majorVersionLabel,
+		CompatMetrics:     compatMetrics,
 	}
 
 	if raw := getEnv(envTimeout); raw != "" {
@@ -231,6 +589,150 @@ func loadConfigFromEnv(getEnv func(string) string) (Config, error) {
 		result.Timeout = value
 	}
 
+	if raw := getEnv(envCollectTimeout); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, err
+		}
+
+		result.CollectTimeout = value
+	}
+
+	if raw := getEnv(envScrapeInterval); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, err
+		}
+
+		result.ScrapeInterval = value
+	}
+
+	if raw := getEnv(envPushInterval); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, err
+		}
+
+		result.PushInterval = value
+	}
+
+	if raw := getEnv(envLeaderLeaseDuration); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, err
+		}
+
+		result.LeaderLeaseDuration = value
+	}
+
+	if raw := getEnv(envCircuitBreakerThreshold); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("can not parse value for %q: %s", envCircuitBreakerThreshold, raw)
+		}
+
+		result.CircuitBreakerThreshold = value
+	}
+
+	if raw := getEnv(envCircuitBreakerCooldown); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, err
+		}
+
+		result.CircuitBreakerCooldown = value
+	}
+
+	if raw := getEnv(envMaxResponseSize); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("can not parse value for %q: %s", envMaxResponseSize, raw)
+		}
+
+		result.MaxResponseSize = value
+	}
+
+	phpEndOfLife, err := parseEnvMap(getEnv, envPHPEndOfLife)
+	if err != nil {
+		return Config{}, err
+	}
+	result.PHPEndOfLife = phpEndOfLife
+
+	labels, err := parseEnvMap(getEnv, envLabels)
+	if err != nil {
+		return Config{}, err
+	}
+	result.Labels = labels
+
+	pushLabels, err := parseEnvMap(getEnv, envPushLabels)
+	if err != nil {
+		return Config{}, err
+	}
+	result.PushLabels = pushLabels
+
+	if raw := getEnv(envRecordRedact); raw != "" {
+		result.RecordRedact = strings.Split(raw, ",")
+	}
+
+	if raw := getEnv(envPHPSettings); raw != "" {
+		result.PHPSettings = strings.Split(raw, ",")
+	}
+
+	if raw := getEnv(envScrapeDurationBuckets); raw != "" {
+		for _, rawBucket := range strings.Split(raw, ",") {
+			value, err := strconv.ParseFloat(rawBucket, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("can not parse value for %q: %s", envScrapeDurationBuckets, raw)
+			}
+
+			result.ScrapeDurationBuckets = append(result.ScrapeDurationBuckets, value)
+		}
+	}
+
+	if raw := getEnv(envAcceptStatusCodes); raw != "" {
+		for _, rawCode := range strings.Split(raw, ",") {
+			value, err := strconv.Atoi(rawCode)
+			if err != nil {
+				return Config{}, fmt.Errorf("can not parse value for %q: %s", envAcceptStatusCodes, raw)
+			}
+
+			result.AcceptStatusCodes = append(result.AcceptStatusCodes, value)
+		}
+	}
+
+	return result, nil
+}
+
+func parseEnvBool(getEnv func(string) string, key string) (bool, error) {
+	rawValue := getEnv(key)
+	if rawValue == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(rawValue)
+	if err != nil {
+		return false, fmt.Errorf("can not parse value for %q: %s", key, rawValue)
+	}
+
+	return value, nil
+}
+
+func parseEnvMap(getEnv func(string) string, key string) (map[string]string, error) {
+	rawValue := getEnv(key)
+	if rawValue == "" {
+		return nil, nil
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(rawValue, ",") {
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("can not parse value for %q: %s", key, rawValue)
+		}
+
+		result[tokens[0]] = tokens[1]
+	}
+
 	return result, nil
 }
 
@@ -240,6 +742,14 @@ func mergeConfig(base, override Config) Config {
 		result.ListenAddr = override.ListenAddr
 	}
 
+	if override.WebConfigFile != "" {
+		result.WebConfigFile = override.WebConfigFile
+	}
+
+	if override.WebRoutePrefix != "" {
+		result.WebRoutePrefix = override.WebRoutePrefix
+	}
+
 	if override.ServerURL != "" {
 		result.ServerURL = override.ServerURL
 	}
@@ -256,14 +766,170 @@ func mergeConfig(base, override Config) Config {
 		result.AuthToken = override.AuthToken
 	}
 
+	if override.AuthTokenQuery {
+		result.AuthTokenQuery = override.AuthTokenQuery
+	}
+
+	if override.AuthFallbackBasic {
+		result.AuthFallbackBasic = override.AuthFallbackBasic
+	}
+
+	if override.BasicAuthUsername != "" {
+		result.BasicAuthUsername = override.BasicAuthUsername
+	}
+
+	if override.BasicAuthPassword != "" {
+		result.BasicAuthPassword = override.BasicAuthPassword
+	}
+
 	if override.Timeout != 0 {
 		result.Timeout = override.Timeout
 	}
 
+	if override.CollectTimeout != 0 {
+		result.CollectTimeout = override.CollectTimeout
+	}
+
+	if override.ScrapeInterval != 0 {
+		result.ScrapeInterval = override.ScrapeInterval
+	}
+
 	if override.TLSSkipVerify {
 		result.TLSSkipVerify = override.TLSSkipVerify
 	}
 
+	if override.TLSCAFile != "" {
+		result.TLSCAFile = override.TLSCAFile
+	}
+
+	if override.TLSCAOnly {
+		result.TLSCAOnly = override.TLSCAOnly
+	}
+
+	if override.DNSServer != "" {
+		result.DNSServer = override.DNSServer
+	}
+
+	if override.Proxy != "" {
+		result.Proxy = override.Proxy
+	}
+
+	if override.CircuitBreakerThreshold != 0 {
+		result.CircuitBreakerThreshold = override.CircuitBreakerThreshold
+	}
+
+	if override.CircuitBreakerCooldown != 0 {
+		result.CircuitBreakerCooldown = override.CircuitBreakerCooldown
+	}
+
+	if override.MetricsPrefix != "" {
+		result.MetricsPrefix = override.MetricsPrefix
+	}
+
+	if override.MajorVersionLabel {
+		result.MajorVersionLabel = override.MajorVersionLabel
+	}
+
+	if override.CompatMetrics {
+		result.CompatMetrics = override.CompatMetrics
+	}
+
+	if len(override.PHPEndOfLife) > 0 {
+		result.PHPEndOfLife = override.PHPEndOfLife
+	}
+
+	if len(override.PHPSettings) > 0 {
+		result.PHPSettings = override.PHPSettings
+	}
+
+	if len(override.ScrapeDurationBuckets) > 0 {
+		result.ScrapeDurationBuckets = override.ScrapeDurationBuckets
+	}
+
+	if override.HTTPTrace {
+		result.HTTPTrace = override.HTTPTrace
+	}
+
+	if override.HeadPrecheck {
+		result.HeadPrecheck = override.HeadPrecheck
+	}
+
+	if override.WebDAVCheck {
+		result.WebDAVCheck = override.WebDAVCheck
+	}
+
+	if override.ReachabilityCheck {
+		result.ReachabilityCheck = override.ReachabilityCheck
+	}
+
+	if len(override.Labels) > 0 {
+		result.Labels = override.Labels
+	}
+
+	if override.APIVersion != "" {
+		result.APIVersion = override.APIVersion
+	}
+
+	if override.MinVersion != "" {
+		result.MinVersion = override.MinVersion
+	}
+
+	if override.MinVersionExit {
+		result.MinVersionExit = override.MinVersionExit
+	}
+
+	if override.SecondaryInfoPath != "" {
+		result.SecondaryInfoPath = override.SecondaryInfoPath
+	}
+
+	if override.RecordFile != "" {
+		result.RecordFile = override.RecordFile
+	}
+
+	if len(override.RecordRedact) > 0 {
+		result.RecordRedact = override.RecordRedact
+	}
+
+	if len(override.AcceptStatusCodes) > 0 {
+		result.AcceptStatusCodes = override.AcceptStatusCodes
+	}
+
+	if override.RetryTruncated {
+		result.RetryTruncated = override.RetryTruncated
+	}
+
+	if override.OutputFile != "" {
+		result.OutputFile = override.OutputFile
+	}
+
+	if override.PushURL != "" {
+		result.PushURL = override.PushURL
+	}
+
+	if override.PushJob != "" {
+		result.PushJob = override.PushJob
+	}
+
+	if override.PushInterval != 0 {
+		result.PushInterval = override.PushInterval
+	}
+
+	if len(override.PushLabels) > 0 {
+		result.PushLabels = override.PushLabels
+	}
+
+	if override.LeaderLockFile != "" {
+		result.LeaderLockFile = override.LeaderLockFile
+	}
+
+	if override.LeaderLeaseDuration != 0 {
+		result.LeaderLeaseDuration = override.LeaderLeaseDuration
+	}
+
+	if override.MaxResponseSize != 0 {
+		result.MaxResponseSize = override.MaxResponseSize
+	}
+
 	return result
 }
 
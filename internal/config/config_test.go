@@ -48,16 +48,28 @@ func TestConfig(t *testing.T) {
 				"testuser",
 				"--password",
 				"testpass",
+				"--metrics-prefix",
+				"custom_",
+				"--scrape-duration-buckets",
+				"0.5,1,5",
 			},
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    "127.0.0.1:9205",
-				Timeout:       30 * time.Second,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: false,
+				ListenAddr:             "127.0.0.1:9205",
+				Timeout:                30 * time.Second,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          "custom_",
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ScrapeDurationBuckets:  []float64{0.5, 1, 5},
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				TLSSkipVerify:          false,
 			},
 		},
 		{
@@ -74,12 +86,50 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    defaults.ListenAddr,
-				Timeout:       defaults.Timeout,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: false,
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				TLSSkipVerify:          false,
+			},
+		},
+		{
+			desc: "basic authentication password from file",
+			args: []string{
+				"test",
+				"--server",
+				"http://localhost",
+				"--auth-token",
+				"auth-token",
+				"--basic-auth-username",
+				"proxyuser",
+				"--basic-auth-password",
+				"@testdata/password",
+			},
+			env:     map[string]string{},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				AuthToken:              "auth-token",
+				BasicAuthUsername:      "proxyuser",
+				BasicAuthPassword:      "testpass",
 			},
 		},
 		{
@@ -92,12 +142,19 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    "127.0.0.10:9205",
-				Timeout:       10 * time.Second,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: false,
+				ListenAddr:             "127.0.0.10:9205",
+				Timeout:                10 * time.Second,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				TLSSkipVerify:          false,
 			},
 		},
 		{
@@ -110,12 +167,19 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    "127.0.0.10:9205",
-				Timeout:       10 * time.Second,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: false,
+				ListenAddr:             "127.0.0.10:9205",
+				Timeout:                10 * time.Second,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				TLSSkipVerify:          false,
 			},
 		},
 		{
@@ -128,12 +192,19 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    ":9205",
-				Timeout:       5 * time.Second,
-				ServerURL:     "",
-				Username:      "",
-				Password:      "",
-				TLSSkipVerify: true,
+				ListenAddr:             ":9205",
+				Timeout:                5 * time.Second,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "",
+				Username:               "",
+				Password:               "",
+				TLSSkipVerify:          true,
 			},
 		},
 		{
@@ -142,21 +213,97 @@ func TestConfig(t *testing.T) {
 				"test",
 			},
 			env: map[string]string{
-				envListenAddress: "127.0.0.11:9205",
-				envTimeout:       "15s",
-				envServerURL:     "http://localhost",
-				envUsername:      "testuser",
-				envPassword:      "testpass",
-				envTLSSkipVerify: "true",
+				envListenAddress:           "127.0.0.11:9205",
+				envTimeout:                 "15s",
+				envCollectTimeout:          "10s",
+				envScrapeInterval:          "1m",
+				envServerURL:               "http://localhost",
+				envUsername:                "testuser",
+				envPassword:                "testpass",
+				envTLSSkipVerify:           "true",
+				envCircuitBreakerThreshold: "3",
+				envCircuitBreakerCooldown:  "5m",
+				envPHPEndOfLife:            "8.1=2025-12-31,8.2=2026-12-31",
+				envScrapeDurationBuckets:   "0.1,1",
+				envHTTPTrace:               "true",
+				envHeadPrecheck:            "true",
+				envWebDAVCheck:             "true",
+				envBasicAuthUsername:       "proxyuser",
+				envBasicAuthPassword:       "proxypass",
+				envReachabilityCheck:       "true",
+				envMaxResponseSize:         "1048576",
+				envAPIVersion:              "v2",
+				envMinVersion:              "27.1",
+				envMinVersionExit:          "true",
+				envSecondaryInfoPath:       "/ocs/v2.php/apps/serverinfo/api/v1/apps?format=json",
+				envDNSServer:               "10.0.0.53",
+				envProxy:                   "socks5://localhost:1080",
+				envTLSCAFile:               "/etc/ssl/internal-ca.pem",
+				envTLSCAOnly:               "true",
+				envMajorVersionLabel:       "true",
+				envCompatMetrics:           "true",
+				envRecordFile:              "/tmp/serverinfo.json",
+				envWebConfigFile:           "/etc/nextcloud-exporter/web.yml",
+				envWebRoutePrefix:          "/nextcloud-exporter",
+				envPushURL:                 "http://pushgateway:9091",
+				envPushJob:                 "cloud",
+				envPushInterval:            "30s",
+				envLeaderLockFile:          "/shared/leader.lock",
+				envLeaderLeaseDuration:     "30s",
+				envPushLabels:              "instance=cloud1",
+				envRecordRedact:            "version,size",
+				envPHPSettings:             "max_execution_time,output_buffering",
+				envAcceptStatusCodes:       "203,206",
+				envRetryTruncated:          "true",
 			},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    "127.0.0.11:9205",
-				Timeout:       15 * time.Second,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: true,
+				ListenAddr:              "127.0.0.11:9205",
+				Timeout:                 15 * time.Second,
+				CollectTimeout:          10 * time.Second,
+				ScrapeInterval:          time.Minute,
+				ServerURL:               "http://localhost",
+				Username:                "testuser",
+				Password:                "testpass",
+				TLSSkipVerify:           true,
+				CircuitBreakerThreshold: 3,
+				CircuitBreakerCooldown:  5 * time.Minute,
+				MetricsPrefix:           defaults.MetricsPrefix,
+				MaxResponseSize:         1048576,
+				APIVersion:              "v2",
+				MinVersion:              "27.1",
+				MinVersionExit:          true,
+				SecondaryInfoPath:       "/ocs/v2.php/apps/serverinfo/api/v1/apps?format=json",
+				PushURL:                 "http://pushgateway:9091",
+				PushJob:                 "cloud",
+				PushLabels:              map[string]string{"instance": "cloud1"},
+				PushInterval:            30 * time.Second,
+				LeaderLockFile:          "/shared/leader.lock",
+				LeaderLeaseDuration:     30 * time.Second,
+				DNSServer:               "10.0.0.53",
+				Proxy:                   "socks5://localhost:1080",
+				TLSCAFile:               "/etc/ssl/internal-ca.pem",
+				TLSCAOnly:               true,
+				MajorVersionLabel:       true,
+				CompatMetrics:           true,
+				RecordFile:              "/tmp/serverinfo.json",
+				WebConfigFile:           "/etc/nextcloud-exporter/web.yml",
+				WebRoutePrefix:          "/nextcloud-exporter",
+				RecordRedact:            []string{"version", "size"},
+				PHPSettings:             []string{"max_execution_time", "output_buffering"},
+				AcceptStatusCodes:       []int{203, 206},
+				RetryTruncated:          true,
+				PHPEndOfLife: map[string]string{
+					"8.1": "2025-12-31",
+					"8.2": "2026-12-31",
+				},
+				ScrapeDurationBuckets: []float64{0.1, 1},
+				HTTPTrace:             true,
+				HeadPrecheck:          true,
+				WebDAVCheck:           true,
+				BasicAuthUsername:     "proxyuser",
+				BasicAuthPassword:     "proxypass",
+				ReachabilityCheck:     true,
 			},
 		},
 		{
@@ -171,12 +318,19 @@ func TestConfig(t *testing.T) {
 			},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    defaults.ListenAddr,
-				Timeout:       defaults.Timeout,
-				ServerURL:     "http://localhost",
-				Username:      "testuser",
-				Password:      "testpass",
-				TLSSkipVerify: false,
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				TLSSkipVerify:          false,
 			},
 		},
 		{
@@ -189,13 +343,155 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr:    defaults.ListenAddr,
-				Timeout:       defaults.Timeout,
-				ServerURL:     "http://localhost",
-				Username:      "",
-				Password:      "",
-				AuthToken:     "auth-token",
-				TLSSkipVerify: false,
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "",
+				Password:               "",
+				AuthToken:              "auth-token",
+				TLSSkipVerify:          false,
+			},
+		},
+		{
+			desc: "token as query parameter",
+			args: []string{
+				"test",
+				"--auth-token-query",
+			},
+			env: map[string]string{
+				envServerURL: "http://localhost",
+				envAuthToken: "auth-token",
+			},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				AuthToken:              "auth-token",
+				AuthTokenQuery:         true,
+			},
+		},
+		{
+			desc: "fallback to basic authentication",
+			args: []string{
+				"test",
+			},
+			env: map[string]string{
+				envServerURL:         "http://localhost",
+				envUsername:          "testuser",
+				envPassword:          "testpass",
+				envAuthToken:         "auth-token",
+				envAuthFallbackBasic: "true",
+			},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				Username:               "testuser",
+				Password:               "testpass",
+				AuthToken:              "auth-token",
+				AuthFallbackBasic:      true,
+			},
+		},
+		{
+			desc: "php end-of-life dates",
+			args: []string{
+				"test",
+				"--php-eol",
+				"8.1=2025-12-31",
+				"--php-eol",
+				"8.2=2026-12-31",
+			},
+			env:     map[string]string{},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				PHPEndOfLife: map[string]string{
+					"8.1": "2025-12-31",
+					"8.2": "2026-12-31",
+				},
+			},
+		},
+		{
+			desc: "static labels",
+			args: []string{
+				"test",
+				"--label",
+				"environment=prod",
+				"--label",
+				"team=infra",
+			},
+			env:     map[string]string{},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				Labels: map[string]string{
+					"environment": "prod",
+					"team":        "infra",
+				},
+			},
+		},
+		{
+			desc: "static labels from env",
+			args: []string{
+				"test",
+			},
+			env: map[string]string{
+				envLabels: "environment=prod,team=infra",
+			},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				Labels: map[string]string{
+					"environment": "prod",
+					"team":        "infra",
+				},
 			},
 		},
 		{
@@ -233,10 +529,68 @@ func TestConfig(t *testing.T) {
 			env:     map[string]string{},
 			wantErr: nil,
 			wantConfig: Config{
-				ListenAddr: defaults.ListenAddr,
-				Timeout:    defaults.Timeout,
-				ServerURL:  "http://localhost",
-				RunMode:    RunModeLogin,
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				RunMode:                RunModeLogin,
+			},
+		},
+		{
+			desc: "check mode",
+			args: []string{
+				"test",
+				"--check",
+				"--server",
+				"http://localhost",
+			},
+			env:     map[string]string{},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				RunMode:                RunModeCheck,
+			},
+		},
+		{
+			desc: "once mode",
+			args: []string{
+				"test",
+				"--once",
+				"--output-file",
+				"/var/lib/node_exporter/nextcloud.prom",
+				"--server",
+				"http://localhost",
+			},
+			env:     map[string]string{},
+			wantErr: nil,
+			wantConfig: Config{
+				ListenAddr:             defaults.ListenAddr,
+				Timeout:                defaults.Timeout,
+				CircuitBreakerCooldown: defaults.CircuitBreakerCooldown,
+				MetricsPrefix:          defaults.MetricsPrefix,
+				MaxResponseSize:        defaults.MaxResponseSize,
+				APIVersion:             defaults.APIVersion,
+				PushJob:                defaults.PushJob,
+				PushInterval:           defaults.PushInterval,
+				LeaderLeaseDuration:    defaults.LeaderLeaseDuration,
+				ServerURL:              "http://localhost",
+				OutputFile:             "/var/lib/node_exporter/nextcloud.prom",
+				RunMode:                RunModeOnce,
 			},
 		},
 		{
@@ -258,6 +612,36 @@ func TestConfig(t *testing.T) {
 			},
 			wantErr: errors.New("error reading environment variables: time: invalid duration \"unknown\""),
 		},
+		{
+			desc: "env wrong circuit breaker threshold",
+			args: []string{
+				"test",
+			},
+			env: map[string]string{
+				envCircuitBreakerThreshold: "many",
+			},
+			wantErr: errors.New(`error reading environment variables: can not parse value for "NEXTCLOUD_CIRCUIT_BREAKER_THRESHOLD": many`),
+		},
+		{
+			desc: "env wrong max response size",
+			args: []string{
+				"test",
+			},
+			env: map[string]string{
+				envMaxResponseSize: "10M",
+			},
+			wantErr: errors.New(`error reading environment variables: can not parse value for "NEXTCLOUD_MAX_RESPONSE_SIZE": 10M`),
+		},
+		{
+			desc: "env wrong php end-of-life dates",
+			args: []string{
+				"test",
+			},
+			env: map[string]string{
+				envPHPEndOfLife: "8.1",
+			},
+			wantErr: errors.New(`error reading environment variables: can not parse value for "NEXTCLOUD_PHP_EOL": 8.1`),
+		},
 		{
 			desc: "password from file error",
 			args: []string{
@@ -337,6 +721,31 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			desc: "info file",
+			config: Config{
+				ServerURL: "file://testdata/info.json",
+			},
+			wantErr: nil,
+		},
+		{
+			desc: "api version",
+			config: Config{
+				ServerURL:  "https://example.com",
+				AuthToken:  "auth-token",
+				APIVersion: "v2",
+			},
+			wantErr: nil,
+		},
+		{
+			desc: "invalid api version",
+			config: Config{
+				ServerURL:  "https://example.com",
+				AuthToken:  "auth-token",
+				APIVersion: "2",
+			},
+			wantErr: errValidateAPIVersion,
+		},
 		{
 			desc: "no url",
 			config: Config{
@@ -368,6 +777,150 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: errValidateNoPassword,
 		},
+		{
+			desc: "push without interval",
+			config: Config{
+				ServerURL: "https://example.com",
+				AuthToken: "auth-token",
+				PushURL:   "http://pushgateway:9091",
+			},
+			wantErr: errValidatePushInterval,
+		},
+		{
+			desc: "ca only without file",
+			config: Config{
+				ServerURL: "https://example.com",
+				AuthToken: "auth-token",
+				TLSCAOnly: true,
+			},
+			wantErr: errValidateNoCAFile,
+		},
+		{
+			desc: "leader without lease duration",
+			config: Config{
+				ServerURL:      "https://example.com",
+				AuthToken:      "auth-token",
+				LeaderLockFile: "/shared/leader.lock",
+			},
+			wantErr: errValidateLeaderLease,
+		},
+		{
+			desc: "invalid accepted status code",
+			config: Config{
+				ServerURL:         "https://example.com",
+				AuthToken:         "auth-token",
+				AcceptStatusCodes: []int{203, 1000},
+			},
+			wantErr: errValidateStatusCode,
+		},
+		{
+			desc: "webdav check with token",
+			config: Config{
+				ServerURL:   "https://example.com",
+				AuthToken:   "auth-token",
+				WebDAVCheck: true,
+			},
+			wantErr: errValidateWebDAVNoAuth,
+		},
+		{
+			desc: "relative secondary info path",
+			config: Config{
+				ServerURL:         "https://example.com",
+				AuthToken:         "auth-token",
+				SecondaryInfoPath: "ocs/v2.php/apps/serverinfo/api/v1/apps",
+			},
+			wantErr: errValidateSecondaryInfoPath,
+		},
+		{
+			desc: "invalid minimum version",
+			config: Config{
+				ServerURL:  "https://example.com",
+				AuthToken:  "auth-token",
+				MinVersion: "27.x",
+			},
+			wantErr: errValidateMinVersion,
+		},
+		{
+			desc: "invalid minimum version with file",
+			config: Config{
+				ServerURL:  "file://testdata/info.json",
+				MinVersion: "27.x",
+			},
+			wantErr: errValidateMinVersion,
+		},
+		{
+			desc: "minimum version with file",
+			config: Config{
+				ServerURL:  "file://testdata/info.json",
+				MinVersion: "21.0",
+			},
+			wantErr: nil,
+		},
+		{
+			desc: "socks5 proxy",
+			config: Config{
+				ServerURL: "https://example.com",
+				AuthToken: "auth-token",
+				Proxy:     "socks5://localhost:1080",
+			},
+			wantErr: nil,
+		},
+		{
+			desc: "proxy without scheme",
+			config: Config{
+				ServerURL: "https://example.com",
+				AuthToken: "auth-token",
+				Proxy:     "localhost:1080",
+			},
+			wantErr: errValidateProxy,
+		},
+		{
+			desc: "fallback without password",
+			config: Config{
+				ServerURL:         "https://example.com",
+				Username:          "exporter",
+				AuthToken:         "auth-token",
+				AuthFallbackBasic: true,
+			},
+			wantErr: errValidateNoFallback,
+		},
+		{
+			desc: "basic authentication without password",
+			config: Config{
+				ServerURL:         "https://example.com",
+				AuthToken:         "auth-token",
+				BasicAuthUsername: "proxyuser",
+			},
+			wantErr: errValidateBasicAuth,
+		},
+		{
+			desc: "basic authentication without token",
+			config: Config{
+				ServerURL:         "https://example.com",
+				Username:          "exporter",
+				Password:          "password",
+				BasicAuthUsername: "proxyuser",
+				BasicAuthPassword: "proxypass",
+			},
+			wantErr: errValidateBasicAuthNoToken,
+		},
+		{
+			desc: "duplicate php setting",
+			config: Config{
+				ServerURL:   "https://example.com",
+				AuthToken:   "auth-token",
+				PHPSettings: []string{"output_buffering", "output_buffering"},
+			},
+			wantErr: errValidatePHPSettings,
+		},
+		{
+			desc: "duplicate php setting with file",
+			config: Config{
+				ServerURL:   "file://testdata/info.json",
+				PHPSettings: []string{"output_buffering", "output_buffering"},
+			},
+			wantErr: errValidatePHPSettings,
+		},
 	}
 
 	for _, tc := range tt {
@@ -383,3 +936,48 @@ func TestConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigRoutePrefix(t *testing.T) {
+	tt := []struct {
+		desc       string
+		prefix     string
+		wantPrefix string
+	}{
+		{
+			desc:       "empty",
+			prefix:     "",
+			wantPrefix: "",
+		},
+		{
+			desc:       "only slash",
+			prefix:     "/",
+			wantPrefix: "",
+		},
+		{
+			desc:       "without slashes",
+			prefix:     "nextcloud-exporter",
+			wantPrefix: "/nextcloud-exporter",
+		},
+		{
+			desc:       "with slashes",
+			prefix:     "/nextcloud-exporter/",
+			wantPrefix: "/nextcloud-exporter",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config := Config{
+				WebRoutePrefix: tc.prefix,
+			}
+
+			prefix := config.RoutePrefix()
+			if prefix != tc.wantPrefix {
+				t.Errorf("got prefix %q, want %q", prefix, tc.wantPrefix)
+			}
+		})
+	}
+}
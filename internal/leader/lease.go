@@ -0,0 +1,141 @@
+package leader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Lease implements a simple leader election using a lock file on storage shared by all instances.
+// The leader renews the lease by writing its ID to the file. When the file has not been modified for
+// the duration of the lease, another instance takes over.
+//
+// The modification time of the file is compared to the clock of the instance, so clocks which are apart by
+// more than half the lease duration can lead to two leaders at the same time. To limit the time two
+// instances are leader when renewing is slow, the leader stops acting as leader when it could not renew
+// the lease for half the lease duration.
+type Lease struct {
+	log      logrus.FieldLogger
+	fileName string
+	id       string
+	duration time.Duration
+
+	mu      sync.Mutex
+	leader  bool
+	renewed time.Time
+}
+
+// New creates a lease using the lock file. The ID needs to be unique for every instance.
+func New(log logrus.FieldLogger, fileName, id string, duration time.Duration) *Lease {
+	return &Lease{
+		log:      log,
+		fileName: fileName,
+		id:       id,
+		duration: duration,
+	}
+}
+
+// IsLeader returns true if this instance currently holds the lease and renewed it during the last half of
+// the lease duration.
+func (l *Lease) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.leader && time.Since(l.renewed) < l.duration/2
+}
+
+// Run tries to acquire or renew the lease three times per lease duration. It does not return.
+func (l *Lease) Run() {
+	ticker := time.NewTicker(l.duration / 3)
+	defer ticker.Stop()
+
+	for {
+		l.update()
+
+		<-ticker.C
+	}
+}
+
+func (l *Lease) update() {
+	leader, err := l.tryAcquire()
+	if err != nil {
+		l.log.Errorf("Error updating leader lease: %s", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if leader != l.leader {
+		if leader {
+			l.log.Info("Acquired leader lease.")
+		} else {
+			l.log.Info("Lost leader lease.")
+		}
+	}
+	l.leader = leader
+	if leader {
+		l.renewed = time.Now()
+	}
+}
+
+func (l *Lease) tryAcquire() (bool, error) {
+	holder, modified, err := l.read()
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, err
+	case holder != l.id && time.Since(modified) < l.duration:
+		return false, nil
+	}
+
+	if err := l.write(); err != nil {
+		return false, err
+	}
+
+	// Another instance might have taken over at the same time, so check that this instance won.
+	holder, _, err = l.read()
+	if err != nil {
+		return false, err
+	}
+
+	return holder == l.id, nil
+}
+
+func (l *Lease) read() (string, time.Time, error) {
+	info, err := os.Stat(l.fileName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	data, err := ioutil.ReadFile(l.fileName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return strings.TrimSpace(string(data)), info.ModTime(), nil
+}
+
+func (l *Lease) write() error {
+	tempFile, err := ioutil.TempFile(filepath.Dir(l.fileName), ".lease-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.WriteString(l.id + "\n"); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), l.fileName)
+}
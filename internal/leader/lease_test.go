@@ -0,0 +1,79 @@
+package leader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLease(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "leader.lock")
+	first := New(logrus.New(), fileName, "first", 15*time.Second)
+	second := New(logrus.New(), fileName, "second", 15*time.Second)
+
+	first.update()
+	if !first.IsLeader() {
+		t.Fatal("first instance did not acquire lease")
+	}
+
+	second.update()
+	if second.IsLeader() {
+		t.Fatal("second instance acquired lease held by first instance")
+	}
+
+	first.update()
+	if !first.IsLeader() {
+		t.Fatal("first instance could not renew lease")
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(fileName, expired, expired); err != nil {
+		t.Fatalf("error changing modification time: %s", err)
+	}
+
+	second.update()
+	if !second.IsLeader() {
+		t.Fatal("second instance did not take over expired lease")
+	}
+
+	first.update()
+	if first.IsLeader() {
+		t.Fatal("first instance still leader after takeover")
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("error reading lease: %s", err)
+	}
+
+	if got := string(data); got != "second\n" {
+		t.Errorf("got lease content %q, want %q", got, "second\n")
+	}
+}
+
+func TestLeaseNotRenewed(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "leader.lock")
+	lease := New(logrus.New(), fileName, "first", 15*time.Second)
+
+	lease.update()
+	if !lease.IsLeader() {
+		t.Fatal("instance did not acquire lease")
+	}
+
+	lease.mu.Lock()
+	lease.renewed = time.Now().Add(-10 * time.Second)
+	lease.mu.Unlock()
+
+	if lease.IsLeader() {
+		t.Fatal("instance still leader after not renewing lease for more than half the lease duration")
+	}
+
+	lease.update()
+	if !lease.IsLeader() {
+		t.Fatal("instance not leader after renewing lease")
+	}
+}
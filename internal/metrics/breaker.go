@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops requests to the server for a cooldown period after a number of consecutive failures.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	// probing is set while the single request allowed in the half-open state has not finished.
+	probing bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       now,
+	}
+}
+
+// allow returns true if a request should be made. Once the cooldown is over the breaker is "half-open"
+// and lets a single request through. Other requests are blocked until that request has finished. If it fails
+// as well, the breaker opens again.
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// isOpen returns true if requests are currently blocked by the breaker, because the cooldown is not over.
+func (b *circuitBreaker) isOpen() bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.now().Before(b.openUntil)
+}
+
+// consecutiveFailures returns the number of requests that failed since the last successful one.
+func (b *circuitBreaker) consecutiveFailures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures
+}
+
+// success resets the breaker after a successful request.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// failure records a failed request and opens the breaker once the threshold is reached.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	current time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.current
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.current = c.current.Add(d)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	tt := []struct {
+		desc         string
+		threshold    int
+		steps        func(b *circuitBreaker, clock *testClock)
+		wantOpen     bool
+		wantFailures int
+	}{
+		{
+			desc:      "disabled",
+			threshold: 0,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+				b.failure()
+			},
+			wantOpen:     false,
+			wantFailures: 3,
+		},
+		{
+			desc:      "below threshold",
+			threshold: 3,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+			},
+			wantOpen:     false,
+			wantFailures: 2,
+		},
+		{
+			desc:      "threshold reached",
+			threshold: 3,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+				b.failure()
+			},
+			wantOpen:     true,
+			wantFailures: 3,
+		},
+		{
+			desc:      "success resets failures",
+			threshold: 3,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+				b.success()
+				b.failure()
+			},
+			wantOpen:     false,
+			wantFailures: 1,
+		},
+		{
+			desc:      "half-open after cooldown",
+			threshold: 1,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				clock.advance(time.Minute)
+			},
+			wantOpen:     false,
+			wantFailures: 1,
+		},
+		{
+			desc:      "still open during cooldown",
+			threshold: 1,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				clock.advance(59 * time.Second)
+			},
+			wantOpen:     true,
+			wantFailures: 1,
+		},
+		{
+			desc:      "reopen on failure when half-open",
+			threshold: 2,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+				clock.advance(time.Minute)
+				b.failure()
+			},
+			wantOpen:     true,
+			wantFailures: 3,
+		},
+		{
+			desc:      "close on success when half-open",
+			threshold: 2,
+			steps: func(b *circuitBreaker, clock *testClock) {
+				b.failure()
+				b.failure()
+				clock.advance(time.Minute)
+				b.success()
+				b.failure()
+			},
+			wantOpen:     false,
+			wantFailures: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := &testClock{
+				current: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+			b := newCircuitBreaker(tc.threshold, time.Minute, clock.now)
+
+			tc.steps(b, clock)
+
+			open := b.isOpen()
+			if open != tc.wantOpen {
+				t.Errorf("got open %v, want %v", open, tc.wantOpen)
+			}
+
+			failures := b.consecutiveFailures()
+			if failures != tc.wantFailures {
+				t.Errorf("got failures %d, want %d", failures, tc.wantFailures)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	clock := &testClock{
+		current: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	b := newCircuitBreaker(1, time.Minute, clock.now)
+
+	b.failure()
+	if b.allow() {
+		t.Fatal("got request allowed during cooldown")
+	}
+
+	clock.advance(time.Minute)
+	if !b.allow() {
+		t.Fatal("got no request allowed after cooldown")
+	}
+
+	if b.allow() {
+		t.Fatal("got second request allowed while half-open")
+	}
+
+	b.failure()
+	if b.allow() {
+		t.Fatal("got request allowed after failed trial")
+	}
+
+	clock.advance(time.Minute)
+	if !b.allow() {
+		t.Fatal("got no request allowed after second cooldown")
+	}
+
+	b.success()
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatal("got request blocked after successful trial")
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricSlice is a collector, which returns previously collected metrics.
+type metricSlice []prometheus.Metric
+
+func (m metricSlice) Describe(chan<- *prometheus.Desc) {}
+
+func (m metricSlice) Collect(ch chan<- prometheus.Metric) {
+	for _, metric := range m {
+		ch <- metric
+	}
+}
+
+// bufferMetrics returns all metrics sent to the channel by the collect function.
+func bufferMetrics(collect func(chan<- prometheus.Metric)) metricSlice {
+	buffer := make(chan prometheus.Metric)
+	done := make(chan metricSlice)
+	go func() {
+		var metrics metricSlice
+		for metric := range buffer {
+			metrics = append(metrics, metric)
+		}
+		done <- metrics
+	}()
+
+	collect(buffer)
+	close(buffer)
+	return <-done
+}
+
+// metricCache keeps previously collected metrics, for example while this instance is not the leader.
+type metricCache struct {
+	mu      sync.Mutex
+	metrics metricSlice
+	queried time.Time
+}
+
+// set stores the metrics together with the time they were queried from the server.
+func (c *metricCache) set(metrics metricSlice, queried time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics
+	c.queried = queried
+}
+
+// get returns the stored metrics and the time they were queried. The time is zero if no metrics were stored yet.
+func (c *metricCache) get() (metricSlice, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.metrics, c.queried
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queryTimer is implemented by collectors which can return metrics of an earlier query of the server.
+type queryTimer interface {
+	// lastQueryTime returns the time the metrics returned by the last collection were queried from the server.
+	lastQueryTime() time.Time
+}
+
+// cacheMetrics show if the returned metrics of the server are served from a cache and how old they are.
+type cacheMetrics struct {
+	servedFromCacheMetric prometheus.Gauge
+	cacheAgeMetric        prometheus.Gauge
+}
+
+func newCacheMetrics() cacheMetrics {
+	return cacheMetrics{
+		servedFromCacheMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "exporter_served_from_cache",
+			Help: "Indicates if the returned metrics of the server are served from the cache of the exporter instead of being queried during the scrape.",
+		}),
+		cacheAgeMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "exporter_cache_age_seconds",
+			Help: "Time since the returned metrics of the server were queried in seconds. Zero if they were queried during the scrape. Not present if no metrics are cached yet.",
+		}),
+	}
+}
+
+func (m cacheMetrics) describe(ch chan<- *prometheus.Desc) {
+	m.servedFromCacheMetric.Describe(ch)
+	m.cacheAgeMetric.Describe(ch)
+}
+
+func (m cacheMetrics) collect(ch chan<- prometheus.Metric, fromCache bool, queried, now time.Time) {
+	servedFromCache := 0.0
+	if fromCache {
+		servedFromCache = 1
+	}
+	m.servedFromCacheMetric.Set(servedFromCache)
+	m.servedFromCacheMetric.Collect(ch)
+
+	age := 0.0
+	switch {
+	case !fromCache:
+	case queried.IsZero():
+		return
+	default:
+		age = now.Sub(queried).Seconds()
+	}
+	m.cacheAgeMetric.Set(age)
+	m.cacheAgeMetric.Collect(ch)
+}
+
+// cachingCollector collects the metrics of another collector in a fixed interval and returns the result
+// of the last collection when it is scraped.
+type cachingCollector struct {
+	collector prometheus.Collector
+	interval  time.Duration
+	now       func() time.Time
+	metrics   metricCache
+
+	intervalMetric    prometheus.Gauge
+	lastRefreshMetric prometheus.Gauge
+	cacheMetrics      cacheMetrics
+}
+
+func newCachingCollector(collector prometheus.Collector, interval time.Duration) *cachingCollector {
+	c := &cachingCollector{
+		collector: collector,
+		interval:  interval,
+		now:       time.Now,
+
+		intervalMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "scrape_interval_seconds",
+			Help: "Interval in which the server is queried in the background.",
+		}),
+		lastRefreshMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "last_refresh_timestamp_seconds",
+			Help: "Time of the last background query of the server as seconds since the Unix epoch.",
+		}),
+		cacheMetrics: newCacheMetrics(),
+	}
+	c.intervalMetric.Set(interval.Seconds())
+
+	return c
+}
+
+// run refreshes the metrics once per interval. It does not return.
+func (c *cachingCollector) run() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.refresh()
+
+		<-ticker.C
+	}
+}
+
+func (c *cachingCollector) refresh() {
+	refreshed := c.now()
+	metrics := bufferMetrics(c.collector.Collect)
+
+	queried := refreshed
+	if timer, ok := c.collector.(queryTimer); ok {
+		// the collector can return metrics it cached itself
+		queried = timer.lastQueryTime()
+	}
+
+	c.metrics.set(metrics, queried)
+	c.lastRefreshMetric.Set(float64(refreshed.UnixNano()) / 1e9)
+}
+
+func (c *cachingCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+	c.intervalMetric.Describe(ch)
+	c.lastRefreshMetric.Describe(ch)
+	c.cacheMetrics.describe(ch)
+}
+
+func (c *cachingCollector) Collect(ch chan<- prometheus.Metric) {
+	metrics, queried := c.metrics.get()
+	metrics.Collect(ch)
+	c.intervalMetric.Collect(ch)
+	c.lastRefreshMetric.Collect(ch)
+	c.cacheMetrics.collect(ch, true, queried, c.now())
+}
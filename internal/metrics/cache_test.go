@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type countingCollector struct {
+	desc  *prometheus.Desc
+	count int
+}
+
+func (c *countingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *countingCollector) Collect(ch chan<- prometheus.Metric) {
+	c.count++
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(c.count))
+}
+
+func TestCachingCollector(t *testing.T) {
+	inner := &countingCollector{
+		desc: prometheus.NewDesc("test_collections", "Test metric.", nil, nil),
+	}
+	cache := newCachingCollector(inner, time.Minute)
+
+	if got := countMetrics(cache); got != 3 {
+		t.Errorf("got %d metrics before refresh, want 3", got)
+	}
+
+	cache.refresh()
+	for i := 0; i < 3; i++ {
+		if got := countMetrics(cache); got != 5 {
+			t.Errorf("got %d metrics after refresh, want 5", got)
+		}
+	}
+
+	if inner.count != 1 {
+		t.Errorf("got %d collections, want 1", inner.count)
+	}
+}
+
+type queryTimeCollector struct {
+	countingCollector
+	queried time.Time
+}
+
+func (c *queryTimeCollector) lastQueryTime() time.Time {
+	return c.queried
+}
+
+func TestCachingCollectorAge(t *testing.T) {
+	start := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		desc      string
+		collector prometheus.Collector
+		want      string
+	}{
+		{
+			desc: "queried during refresh",
+			collector: &countingCollector{
+				desc: prometheus.NewDesc("test_collections", "Test metric.", nil, nil),
+			},
+			want: `# HELP exporter_cache_age_seconds Time since the returned metrics of the server were queried in seconds. Zero if they were queried during the scrape. Not present if no metrics are cached yet.
+# TYPE exporter_cache_age_seconds gauge
+exporter_cache_age_seconds 30
+# HELP exporter_served_from_cache Indicates if the returned metrics of the server are served from the cache of the exporter instead of being queried during the scrape.
+# TYPE exporter_served_from_cache gauge
+exporter_served_from_cache 1
+`,
+		},
+		{
+			desc: "cached by collector",
+			collector: &queryTimeCollector{
+				countingCollector: countingCollector{
+					desc: prometheus.NewDesc("test_collections", "Test metric.", nil, nil),
+				},
+				queried: start.Add(-time.Minute),
+			},
+			want: `# HELP exporter_cache_age_seconds Time since the returned metrics of the server were queried in seconds. Zero if they were queried during the scrape. Not present if no metrics are cached yet.
+# TYPE exporter_cache_age_seconds gauge
+exporter_cache_age_seconds 90
+# HELP exporter_served_from_cache Indicates if the returned metrics of the server are served from the cache of the exporter instead of being queried during the scrape.
+# TYPE exporter_served_from_cache gauge
+exporter_served_from_cache 1
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			now := start
+			cache := newCachingCollector(tc.collector, time.Minute)
+			cache.now = func() time.Time {
+				return now
+			}
+
+			cache.refresh()
+			now = now.Add(30 * time.Second)
+
+			if err := testutil.CollectAndCompare(cache, strings.NewReader(tc.want), "exporter_cache_age_seconds", "exporter_served_from_cache"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func countMetrics(collector prometheus.Collector) int {
+	return len(bufferMetrics(collector.Collect))
+}
@@ -1,7 +1,13 @@
 package metrics
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -10,129 +16,672 @@ import (
 )
 
 const (
-	metricPrefix = "nextcloud_"
-
-	labelErrorCauseOther = "other"
-	labelErrorCauseAuth  = "auth"
+	labelErrorCauseOther      = "other"
+	labelErrorCauseAuth       = "auth"
+	labelErrorCauseTruncated  = "truncated"
+	labelErrorCauseConnection = "connection"
+	labelErrorCauseStatus     = "status"
+	labelErrorCauseParse      = "parse"
+	labelErrorCauseTooLarge   = "too_large"
+	labelErrorCauseOCS        = "ocs"
 )
 
 var (
 	systemInfoDesc = prometheus.NewDesc(
-		metricPrefix+"system_info",
+		"system_info",
 		"Contains meta information about Nextcloud as labels. Value is always 1.",
 		[]string{"version"}, nil)
+	themeInfoDesc = prometheus.NewDesc(
+		"theme_info",
+		"Contains the name of the configured theme as a label. Value is always 1.",
+		[]string{"theme"}, nil)
 	appsInstalledDesc = prometheus.NewDesc(
-		metricPrefix+"apps_installed_total",
+		"apps_installed_total",
 		"Number of currently installed apps",
 		nil, nil)
 	appsUpdatesDesc = prometheus.NewDesc(
-		metricPrefix+"apps_updates_available_total",
+		"apps_updates_available_total",
 		"Number of apps that have available updates",
 		nil, nil)
 	usersDesc = prometheus.NewDesc(
-		metricPrefix+"users_total",
+		"users_total",
 		"Number of users of the instance.",
 		nil, nil)
 	filesDesc = prometheus.NewDesc(
-		metricPrefix+"files_total",
+		"files_total",
 		"Number of files served by the instance.",
 		nil, nil)
 	freeSpaceDesc = prometheus.NewDesc(
-		metricPrefix+"free_space_bytes",
+		"free_space_bytes",
 		"Free disk space in data directory in bytes.",
 		nil, nil)
 	sharesDesc = prometheus.NewDesc(
-		metricPrefix+"shares_total",
+		"shares_total",
 		"Number of shares by type.",
 		[]string{"type"}, nil)
+	sharesLinkNoPasswordDesc = prometheus.NewDesc(
+		"shares_link_nopassword_total",
+		"Number of shared links without password protection.",
+		nil, nil)
+	sharesFileDropDesc = prometheus.NewDesc(
+		"shares_filedrop_total",
+		"Number of public links which only allow uploading files.",
+		nil, nil)
 	federationsDesc = prometheus.NewDesc(
-		metricPrefix+"shares_federated_total",
+		"shares_federated_total",
 		"Number of federated shares by direction.",
 		[]string{"direction"}, nil)
 	activeUsersDesc = prometheus.NewDesc(
-		metricPrefix+"active_users_total",
+		"active_users_total",
 		"Number of active users for the last five minutes.",
 		nil, nil)
+	activeUsersHourlyDesc = prometheus.NewDesc(
+		"active_users_hourly_total",
+		"Number of active users for the last hour.",
+		nil, nil)
+	activeUsersDailyDesc = prometheus.NewDesc(
+		"active_users_daily_total",
+		"Number of active users for the last 24 hours.",
+		nil, nil)
+	activeUsersPeriodDesc = prometheus.NewDesc(
+		"active_users_period_total",
+		"Number of active users for periods longer than a day. Only reported by newer versions of the serverinfo app.",
+		[]string{"period"}, nil)
+	sectionUpDesc = prometheus.NewDesc(
+		"section_up",
+		"Indicates if a section was contained in the server info.",
+		[]string{"section"}, nil)
 	phpInfoDesc = prometheus.NewDesc(
-		metricPrefix+"php_info",
+		"php_info",
 		"Contains meta information about PHP as labels. Value is always 1.",
 		[]string{"version"}, nil)
+	phpSettingDesc = prometheus.NewDesc(
+		"php_setting",
+		"Value of a configured PHP setting. Booleans are exported as 0 or 1.",
+		[]string{"name"}, nil)
+	phpSettingInfoDesc = prometheus.NewDesc(
+		"php_setting_info",
+		"Contains the value of a configured PHP setting, which is not a number, as label. Value is always 1.",
+		[]string{"name", "value"}, nil)
 	phpMemoryLimitDesc = prometheus.NewDesc(
-		metricPrefix+"php_memory_limit_bytes",
+		"php_memory_limit_bytes",
 		"Configured PHP memory limit in bytes.",
 		nil, nil)
 	phpMaxUploadSizeDesc = prometheus.NewDesc(
-		metricPrefix+"php_upload_max_size_bytes",
+		"php_upload_max_size_bytes",
 		"Configured maximum upload size in bytes.",
 		nil, nil)
 	databaseSizeDesc = prometheus.NewDesc(
-		metricPrefix+"database_size_bytes",
+		"database_size_bytes",
 		"Size of database in bytes as reported from engine.",
 		nil, nil)
+	phpFPMProcessesDesc = prometheus.NewDesc(
+		"php_fpm_processes",
+		"Number of PHP-FPM processes by state. The total state contains all processes of the pool.",
+		[]string{"state"}, nil)
+	phpFPMMaxChildrenReachedDesc = prometheus.NewDesc(
+		"php_fpm_max_children_reached_total",
+		"Number of times the PHP-FPM process limit has been reached.",
+		nil, nil)
+	phpOPcacheRestartsDesc = prometheus.NewDesc(
+		"php_opcache_restarts_total",
+		"Number of restarts of the PHP opcode cache by reason.",
+		[]string{"reason"}, nil)
+	phpOPcacheHitRateDesc = prometheus.NewDesc(
+		"php_opcache_hit_rate",
+		"Ratio of hits of the PHP opcode cache to all accesses (0-1).",
+		nil, nil)
+	phpAPCuHitRateDesc = prometheus.NewDesc(
+		"php_apcu_hit_rate",
+		"Ratio of hits of the APCu cache to all accesses (0-1).",
+		nil, nil)
+	phpVersionEOLDesc = prometheus.NewDesc(
+		"php_version_eol",
+		"Indicates if the PHP version has reached its end of life.",
+		nil, nil)
+	scrapePhaseDurationDesc = prometheus.NewDesc(
+		"scrape_phase_duration_seconds",
+		"Duration of the phases of the last request to the server info endpoint in seconds.",
+		[]string{"phase"}, nil)
+	clockSkewDesc = prometheus.NewDesc(
+		"clock_skew_seconds",
+		"Difference between the time reported by the server and the time of the exporter in seconds. Positive values mean the server clock is ahead.",
+		nil, nil)
+	webdavReachableDesc = prometheus.NewDesc(
+		"webdav_reachable",
+		"Indicates if the WebDAV endpoint answered an authenticated request.",
+		nil, nil)
+	reachableDesc = prometheus.NewDesc(
+		"reachable",
+		"Indicates if the server accepted a TCP connection using the address family.",
+		[]string{"family"}, nil)
+	ocsStatusCodeDesc = prometheus.NewDesc(
+		"last_ocs_status_code",
+		"Status code contained in the OCS meta information of the last server info response.",
+		nil, nil)
+	httpsEnforcedDesc = prometheus.NewDesc(
+		"https_enforced",
+		"Indicates if the server info was served using HTTPS with a Strict-Transport-Security header.",
+		nil, nil)
 )
 
+const (
+	headerHSTS = "Strict-Transport-Security"
+	headerDate = "Date"
+)
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// CollectorOptions contains optional settings for the collector.
+type CollectorOptions struct {
+	// CircuitBreakerThreshold is the number of consecutive failed scrapes after which no further requests
+	// are sent to the server for the duration of CircuitBreakerCooldown. Zero disables the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// PHPEndOfLife contains end-of-life dates (YYYY-MM-DD) by PHP version ("8.1"). The entries are merged
+	// with the built-in dates.
+	PHPEndOfLife map[string]string
+	// PHPSettings contains the keys of the PHP section of the server info, which are exported as settings.
+	PHPSettings []string
+	// ScrapeDurationBuckets contains the buckets of the scrape duration histogram. Uses the default buckets if empty.
+	ScrapeDurationBuckets []float64
+	// CollectTimeout limits the time for requesting and parsing the server info. Zero disables the limit.
+	CollectTimeout time.Duration
+	// MajorVersionLabel adds the major version of the server as a label to all metrics of the collector.
+	MajorVersionLabel bool
+	// IsLeader is used for deciding if the server should be queried. When it returns false, the metrics of the
+	// last successful scrape are returned instead. The server is always queried if IsLeader is not set.
+	IsLeader func() bool
+	// ScrapeInterval enables querying the server in the background once per interval instead of on every scrape.
+	// The metrics of the last query are returned when scraping. Zero disables the background queries.
+	ScrapeInterval time.Duration
+	// CompatMetrics enables metrics, which have been replaced in previous versions, for compatibility with
+	// existing dashboards.
+	CompatMetrics bool
+	// WebDAVCheck is called on every scrape if set, for checking that the WebDAV endpoint used by the sync clients works.
+	WebDAVCheck client.WebDAVCheck
+	// ReachabilityCheck is called on every scrape if set, for checking that the server can be reached using IPv4 and IPv6.
+	ReachabilityCheck client.ReachabilityCheck
+}
+
 type nextcloudCollector struct {
-	log        logrus.FieldLogger
-	infoClient client.InfoClient
+	log               logrus.FieldLogger
+	infoClient        client.InfoClient
+	webdavCheck       client.WebDAVCheck
+	reachabilityCheck client.ReachabilityCheck
+	breaker           *circuitBreaker
+	collectTimeout    time.Duration
+	versions          *versionTracker
+	isLeader          func() bool
+	lastMetrics       metricCache
+	phpEOL            map[string]time.Time
+	phpSettings       []string
+	now               func() time.Time
+
+	// cached is set when the collector is wrapped by the cachingCollector, which exports the cache metrics.
+	cached       bool
+	queriedMu    sync.Mutex
+	queried      time.Time
+	cacheMetrics cacheMetrics
 
 	upMetric           prometheus.Gauge
 	scrapeErrorsMetric *prometheus.CounterVec
+	circuitOpenMetric  prometheus.Gauge
+	failuresMetric     prometheus.Gauge
+	lastSuccessMetric  prometheus.Gauge
+	durationMetric     prometheus.Histogram
+	authErrorsMetric   prometheus.Counter
 }
 
-func RegisterCollector(log logrus.FieldLogger, infoClient client.InfoClient) error {
+func RegisterCollector(registerer prometheus.Registerer, log logrus.FieldLogger, infoClient client.InfoClient, opts CollectorOptions) error {
+	phpEOL, err := parseEndOfLife(opts.PHPEndOfLife)
+	if err != nil {
+		return err
+	}
+
+	durationBuckets := opts.ScrapeDurationBuckets
+	if len(durationBuckets) == 0 {
+		durationBuckets = prometheus.DefBuckets
+	}
+
 	c := &nextcloudCollector{
-		log:        log,
-		infoClient: infoClient,
+		log:               log,
+		infoClient:        infoClient,
+		webdavCheck:       opts.WebDAVCheck,
+		reachabilityCheck: opts.ReachabilityCheck,
+		breaker:           newCircuitBreaker(opts.CircuitBreakerThreshold, opts.CircuitBreakerCooldown, time.Now),
+		collectTimeout:    opts.CollectTimeout,
+		versions:          versionsFor(opts.MajorVersionLabel),
+		isLeader:          opts.IsLeader,
+		phpEOL:            phpEOL,
+		phpSettings:       opts.PHPSettings,
+		now:               time.Now,
+		cached:            opts.ScrapeInterval > 0,
+		cacheMetrics:      newCacheMetrics(),
 
 		upMetric: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: metricPrefix + "up",
+			Name: "up",
 			Help: "Indicates if the metrics could be scraped by the exporter.",
 		}),
 		scrapeErrorsMetric: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Name: metricPrefix + "scrape_errors_total",
+			Name: "scrape_errors_total",
 			Help: "Counts the number of scrape errors by this collector.",
 		}, []string{"cause"}),
+		circuitOpenMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "circuit_open",
+			Help: "Indicates if the circuit breaker is open and the server is not queried.",
+		}),
+		failuresMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "consecutive_scrape_failures",
+			Help: "Number of scrapes that failed in a row. Reset to zero after a successful scrape. Scrapes skipped while the circuit breaker is open are not counted.",
+		}),
+		lastSuccessMetric: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "last_scrape_success_timestamp_seconds",
+			Help: "Time of the last successful query of the server as seconds since the Unix epoch. Zero if no query succeeded yet.",
+		}),
+		durationMetric: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "scrape_duration_histogram_seconds",
+			Help:    "Duration of requests to the server info endpoint in seconds.",
+			Buckets: durationBuckets,
+		}),
+	}
+
+	if opts.CompatMetrics {
+		c.authErrorsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_errors_total",
+			Help: "Deprecated: use scrape_errors_total with cause \"auth\". Counts number of authentication errors encountered by the collector.",
+		})
+	}
+
+	if opts.ScrapeInterval > 0 {
+		cache := newCachingCollector(c, opts.ScrapeInterval)
+		if err := registerer.Register(cache); err != nil {
+			return err
+		}
+
+		go cache.run()
+		return nil
 	}
 
-	return prometheus.Register(c)
+	return registerer.Register(c)
+}
+
+func versionsFor(enabled bool) *versionTracker {
+	if !enabled {
+		return nil
+	}
+
+	return newVersionTracker()
 }
 
 func (c *nextcloudCollector) Describe(ch chan<- *prometheus.Desc) {
+	if c.versions != nil {
+		// The descriptors depend on the version label, so the collector is registered as unchecked.
+		return
+	}
+
 	c.upMetric.Describe(ch)
 	c.scrapeErrorsMetric.Describe(ch)
+	c.circuitOpenMetric.Describe(ch)
+	c.failuresMetric.Describe(ch)
+	c.lastSuccessMetric.Describe(ch)
+	if !c.cached {
+		c.cacheMetrics.describe(ch)
+	}
+	c.durationMetric.Describe(ch)
+	if c.authErrorsMetric != nil {
+		c.authErrorsMetric.Describe(ch)
+	}
 	ch <- usersDesc
 	ch <- filesDesc
 	ch <- freeSpaceDesc
 	ch <- sharesDesc
+	ch <- sharesLinkNoPasswordDesc
 	ch <- federationsDesc
 	ch <- activeUsersDesc
+	ch <- activeUsersHourlyDesc
+	ch <- activeUsersDailyDesc
+	ch <- activeUsersPeriodDesc
+	ch <- httpsEnforcedDesc
 }
 
 func (c *nextcloudCollector) Collect(ch chan<- prometheus.Metric) {
-	if err := c.collectNextcloud(ch); err != nil {
-		c.log.Errorf("Error during scrape: %s", err)
+	if c.versions == nil {
+		c.collect(ch)
+		return
+	}
 
-		cause := labelErrorCauseOther
-		if err == client.ErrNotAuthorized {
-			cause = labelErrorCauseAuth
+	collectWithLabels(ch, func() prometheus.Labels {
+		return prometheus.Labels{
+			labelMajorVersion: c.versions.get(),
 		}
-		c.scrapeErrorsMetric.WithLabelValues(cause).Inc()
-		c.upMetric.Set(0)
+	}, c.collect)
+}
+
+func (c *nextcloudCollector) collect(ch chan<- prometheus.Metric) {
+	fromCache := false
+	queried := c.now()
+	if c.isLeader == nil || c.isLeader() {
+		c.scrape(ch)
 	} else {
-		c.upMetric.Set(1)
+		var metrics metricSlice
+		metrics, queried = c.lastMetrics.get()
+		metrics.Collect(ch)
+		fromCache = true
+	}
+
+	c.queriedMu.Lock()
+	c.queried = queried
+	c.queriedMu.Unlock()
+	if !c.cached {
+		c.cacheMetrics.collect(ch, fromCache, queried, c.now())
 	}
 
+	circuitOpen := 0.0
+	if c.breaker.isOpen() {
+		circuitOpen = 1
+	}
+	c.circuitOpenMetric.Set(circuitOpen)
+	c.failuresMetric.Set(float64(c.breaker.consecutiveFailures()))
+
 	c.upMetric.Collect(ch)
 	c.scrapeErrorsMetric.Collect(ch)
+	c.circuitOpenMetric.Collect(ch)
+	c.failuresMetric.Collect(ch)
+	c.lastSuccessMetric.Collect(ch)
+	c.durationMetric.Collect(ch)
+	if c.authErrorsMetric != nil {
+		c.authErrorsMetric.Collect(ch)
+	}
+}
+
+func (c *nextcloudCollector) scrape(ch chan<- prometheus.Metric) {
+	switch err := c.collectServer(ch); {
+	case err == errCircuitOpen:
+		c.log.Debugf("Skipping scrape: %s", err)
+		c.upMetric.Set(0)
+	case err != nil:
+		c.log.Errorf("Error during scrape: %s", err)
+
+		cause := errorCause(err)
+		c.scrapeErrorsMetric.WithLabelValues(cause).Inc()
+		if cause == labelErrorCauseAuth && c.authErrorsMetric != nil {
+			c.authErrorsMetric.Inc()
+		}
+		c.upMetric.Set(0)
+		c.breaker.failure()
+	default:
+		c.upMetric.Set(1)
+		c.lastSuccessMetric.Set(float64(c.now().UnixNano()) / 1e9)
+		c.breaker.success()
+	}
+}
+
+// errorCause returns the value of the cause label for an error of a scrape.
+func errorCause(err error) string {
+	var (
+		statusErr     *client.HTTPStatusError
+		parseErr      *client.ParseError
+		connectionErr *client.ConnectionError
+		tooLargeErr   client.ResponseTooLargeError
+		ocsErr        *serverinfo.OCSError
+	)
+
+	switch {
+	case errors.Is(err, client.ErrNotAuthorized):
+		return labelErrorCauseAuth
+	case errors.Is(err, client.ErrTruncatedResponse):
+		return labelErrorCauseTruncated
+	case errors.As(err, &tooLargeErr):
+		return labelErrorCauseTooLarge
+	case errors.As(err, &ocsErr):
+		return labelErrorCauseOCS
+	case errors.As(err, &statusErr):
+		return labelErrorCauseStatus
+	case errors.As(err, &parseErr):
+		return labelErrorCauseParse
+	case errors.As(err, &connectionErr):
+		return labelErrorCauseConnection
+	default:
+		return labelErrorCauseOther
+	}
+}
+
+func (c *nextcloudCollector) lastQueryTime() time.Time {
+	c.queriedMu.Lock()
+	defer c.queriedMu.Unlock()
+
+	return c.queried
+}
+
+// collectServer collects the metrics from the server. The metrics are kept for serving them while not being
+// the leader.
+func (c *nextcloudCollector) collectServer(ch chan<- prometheus.Metric) error {
+	if c.isLeader == nil {
+		return c.collectNextcloud(ch)
+	}
+
+	var err error
+	queried := c.now()
+	metrics := bufferMetrics(func(ch chan<- prometheus.Metric) {
+		err = c.collectNextcloud(ch)
+	})
+	if err == nil {
+		c.lastMetrics.set(metrics, queried)
+	}
+	metrics.Collect(ch)
+
+	return err
 }
 
 func (c *nextcloudCollector) collectNextcloud(ch chan<- prometheus.Metric) error {
-	status, err := c.infoClient()
+	if !c.breaker.allow() {
+		return errCircuitOpen
+	}
+
+	ctx := context.Background()
+	if c.collectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.collectTimeout)
+		defer cancel()
+	}
+
+	if c.webdavCheck != nil {
+		if err := c.collectWebDAV(ctx, ch); err != nil {
+			return err
+		}
+	}
+
+	if c.reachabilityCheck != nil {
+		if err := collectReachability(ch, c.reachabilityCheck(ctx)); err != nil {
+			return err
+		}
+	}
+
+	start := c.now()
+	res, err := c.infoClient(ctx)
+	c.durationMetric.Observe(c.now().Sub(start).Seconds())
+	var ocsErr *serverinfo.OCSError
+	if errors.As(err, &ocsErr) {
+		if err := collectOCSStatusCode(ch, ocsErr.StatusCode); err != nil {
+			return err
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	return readMetrics(ch, status)
+	if err := collectOCSStatusCode(ch, res.Info.Meta.StatusCode); err != nil {
+		return err
+	}
+
+	if c.versions != nil {
+		c.versions.set(res.Info.Data.Nextcloud.System.Version)
+	}
+
+	if err := readMetrics(ch, res.Info); err != nil {
+		return err
+	}
+
+	if err := collectPHPVersionEOL(ch, res.Info.Data.Server.PHP.Version, c.phpEOL, c.now()); err != nil {
+		return err
+	}
+
+	if err := collectPHPSettings(ch, res.Info.Data.Server.PHP.Settings, c.phpSettings); err != nil {
+		return err
+	}
+
+	return collectResponseMetrics(ch, res)
+}
+
+func (c *nextcloudCollector) collectWebDAV(ctx context.Context, ch chan<- prometheus.Metric) error {
+	reachable := 1.0
+	if err := c.webdavCheck(ctx); err != nil {
+		c.log.Warnf("WebDAV check failed: %s", err)
+		reachable = 0
+	}
+
+	metric, err := prometheus.NewConstMetric(webdavReachableDesc, prometheus.GaugeValue, reachable)
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", webdavReachableDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
+func collectReachability(ch chan<- prometheus.Metric, families map[string]bool) error {
+	for _, family := range []string{client.FamilyIPv4, client.FamilyIPv6} {
+		reachable := 0.0
+		if families[family] {
+			reachable = 1
+		}
+
+		metric, err := prometheus.NewConstMetric(reachableDesc, prometheus.GaugeValue, reachable, family)
+		if err != nil {
+			return fmt.Errorf("error creating metric for %s: %w", reachableDesc, err)
+		}
+		ch <- metric
+	}
+
+	return nil
+}
+
+func collectOCSStatusCode(ch chan<- prometheus.Metric, statusCode int) error {
+	if statusCode == 0 {
+		// response did not contain meta information
+		return nil
+	}
+
+	metric, err := prometheus.NewConstMetric(ocsStatusCodeDesc, prometheus.GaugeValue, float64(statusCode))
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", ocsStatusCodeDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
+func collectPHPVersionEOL(ch chan<- prometheus.Metric, version string, phpEOL map[string]time.Time, now time.Time) error {
+	eol, ok := phpEOL[minorVersion(version)]
+	if !ok {
+		return nil
+	}
+
+	value := 0.0
+	if !now.Before(eol) {
+		value = 1
+	}
+
+	metric, err := prometheus.NewConstMetric(phpVersionEOLDesc, prometheus.GaugeValue, value)
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", phpVersionEOLDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
+// collectPHPSettings exports the values of the settings with the names. Numbers and booleans are exported as value,
+// other values as label. Settings missing in the server info are skipped.
+func collectPHPSettings(ch chan<- prometheus.Metric, settings map[string]interface{}, names []string) error {
+	for _, name := range names {
+		var (
+			metric prometheus.Metric
+			err    error
+		)
+		switch value := settings[name].(type) {
+		case nil:
+			continue
+		case float64:
+			metric, err = prometheus.NewConstMetric(phpSettingDesc, prometheus.GaugeValue, value, name)
+		case bool:
+			enabled := 0.0
+			if value {
+				enabled = 1
+			}
+			metric, err = prometheus.NewConstMetric(phpSettingDesc, prometheus.GaugeValue, enabled, name)
+		default:
+			text := fmt.Sprint(value)
+			if number, parseErr := strconv.ParseFloat(text, 64); parseErr == nil {
+				metric, err = prometheus.NewConstMetric(phpSettingDesc, prometheus.GaugeValue, number, name)
+			} else {
+				metric, err = prometheus.NewConstMetric(phpSettingInfoDesc, prometheus.GaugeValue, 1, name, text)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("error creating metric for PHP setting %q: %w", name, err)
+		}
+		ch <- metric
+	}
+
+	return nil
+}
+
+func collectResponseMetrics(ch chan<- prometheus.Metric, res *client.Response) error {
+	if res.Header == nil {
+		// server info was not read using HTTP
+		return nil
+	}
+
+	httpsEnforced := 0.0
+	if res.TLS != nil && res.Header.Get(headerHSTS) != "" {
+		httpsEnforced = 1
+	}
+
+	metric, err := prometheus.NewConstMetric(httpsEnforcedDesc, prometheus.GaugeValue, httpsEnforced)
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", httpsEnforcedDesc, err)
+	}
+	ch <- metric
+
+	if res.TLS != nil {
+		if err := collectTLSMetrics(ch, res.TLS, res.CertificateError); err != nil {
+			return err
+		}
+	}
+
+	if serverTime, err := http.ParseTime(res.Header.Get(headerDate)); err == nil {
+		skew := serverTime.Sub(res.ReceivedAt).Seconds()
+		metric, err := prometheus.NewConstMetric(clockSkewDesc, prometheus.GaugeValue, skew)
+		if err != nil {
+			return fmt.Errorf("error creating metric for %s: %w", clockSkewDesc, err)
+		}
+		ch <- metric
+	}
+
+	if res.Timings != nil {
+		values := make(map[string]float64)
+		values["dns"] = res.Timings.DNS.Seconds()
+		values["connect"] = res.Timings.Connect.Seconds()
+		values["tls"] = res.Timings.TLSHandshake.Seconds()
+		values["first_byte"] = res.Timings.FirstByte.Seconds()
+		if err := collectMap(ch, scrapePhaseDurationDesc, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func readMetrics(ch chan<- prometheus.Metric, status *serverinfo.ServerInfo) error {
@@ -140,6 +689,10 @@ func readMetrics(ch chan<- prometheus.Metric, status *serverinfo.ServerInfo) err
 		return err
 	}
 
+	if err := collectActiveUsers(ch, status.Data.ActiveUsers); err != nil {
+		return err
+	}
+
 	if err := collectShares(ch, status.Data.Nextcloud.Shares); err != nil {
 		return err
 	}
@@ -148,6 +701,28 @@ func readMetrics(ch chan<- prometheus.Metric, status *serverinfo.ServerInfo) err
 		return err
 	}
 
+	if err := collectFileDropShares(ch, status.Data.Nextcloud.Shares); err != nil {
+		return err
+	}
+
+	if err := collectSections(ch, status.Data.Present); err != nil {
+		return err
+	}
+
+	if err := collectFPM(ch, status.Data.Server.PHP.FPM); err != nil {
+		return err
+	}
+
+	if err := collectOPcache(ch, status.Data.Server.PHP.OPcache); err != nil {
+		return err
+	}
+
+	if apcu := status.Data.Server.PHP.APCu; apcu != nil {
+		if err := collectHitRate(ch, phpAPCuHitRateDesc, apcu.Cache.Hits, apcu.Cache.Misses); err != nil {
+			return err
+		}
+	}
+
 	systemInfo := []string{
 		status.Data.Nextcloud.System.Version,
 	}
@@ -155,6 +730,12 @@ func readMetrics(ch chan<- prometheus.Metric, status *serverinfo.ServerInfo) err
 		return err
 	}
 
+	if theme := status.Data.Nextcloud.System.Theme; theme != "" {
+		if err := collectInfoMetric(ch, themeInfoDesc, []string{theme}); err != nil {
+			return err
+		}
+	}
+
 	phpInfo := []string{
 		status.Data.Server.PHP.Version,
 	}
@@ -191,8 +772,8 @@ func collectSimpleMetrics(ch chan<- prometheus.Metric, status *serverinfo.Server
 			value: float64(status.Data.Nextcloud.System.FreeSpace),
 		},
 		{
-			desc:  activeUsersDesc,
-			value: float64(status.Data.ActiveUsers.Last5Minutes),
+			desc:  sharesLinkNoPasswordDesc,
+			value: float64(status.Data.Nextcloud.Shares.SharesLinkNoPassword),
 		},
 		{
 			desc:  phpMemoryLimitDesc,
@@ -218,12 +799,47 @@ func collectSimpleMetrics(ch chan<- prometheus.Metric, status *serverinfo.Server
 	return nil
 }
 
+func collectActiveUsers(ch chan<- prometheus.Metric, activeUsers serverinfo.ActiveUsers) error {
+	metrics := []struct {
+		desc  *prometheus.Desc
+		value float64
+	}{
+		{
+			desc:  activeUsersDesc,
+			value: float64(activeUsers.Last5Minutes),
+		},
+		{
+			desc:  activeUsersHourlyDesc,
+			value: float64(activeUsers.LastHour),
+		},
+		{
+			desc:  activeUsersDailyDesc,
+			value: float64(activeUsers.LastDay),
+		},
+	}
+	for _, m := range metrics {
+		metric, err := prometheus.NewConstMetric(m.desc, prometheus.GaugeValue, m.value)
+		if err != nil {
+			return fmt.Errorf("error creating metric for %s: %w", m.desc, err)
+		}
+		ch <- metric
+	}
+
+	values := make(map[string]float64)
+	for period, count := range activeUsers.Periods {
+		values[period] = float64(count)
+	}
+
+	return collectMap(ch, activeUsersPeriodDesc, values)
+}
+
 func collectShares(ch chan<- prometheus.Metric, shares serverinfo.Shares) error {
 	values := make(map[string]float64)
 	values["user"] = float64(shares.SharesUser)
 	values["group"] = float64(shares.SharesGroups)
 	values["authlink"] = float64(shares.SharesLink - shares.SharesLinkNoPassword)
 	values["link"] = float64(shares.SharesLink)
+	values["room"] = float64(shares.SharesRoom)
 
 	return collectMap(ch, sharesDesc, values)
 }
@@ -236,11 +852,103 @@ func collectFederatedShares(ch chan<- prometheus.Metric, shares serverinfo.Share
 	return collectMap(ch, federationsDesc, values)
 }
 
+func collectFileDropShares(ch chan<- prometheus.Metric, shares serverinfo.Shares) error {
+	if shares.Permissions == nil {
+		// server does not report shares by permissions
+		return nil
+	}
+
+	count := shares.Permissions[serverinfo.SharePermissions{
+		ShareType:   serverinfo.ShareTypeLink,
+		Permissions: serverinfo.PermissionCreate,
+	}]
+
+	metric, err := prometheus.NewConstMetric(sharesFileDropDesc, prometheus.GaugeValue, float64(count))
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", sharesFileDropDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
+func collectSections(ch chan<- prometheus.Metric, present map[string]bool) error {
+	values := make(map[string]float64)
+	for _, section := range serverinfo.Sections {
+		if present[section] {
+			values[section] = 1
+		} else {
+			values[section] = 0
+		}
+	}
+
+	return collectMap(ch, sectionUpDesc, values)
+}
+
+func collectFPM(ch chan<- prometheus.Metric, fpm *serverinfo.FPM) error {
+	if fpm == nil {
+		return nil
+	}
+
+	values := make(map[string]float64)
+	values["active"] = float64(fpm.ActiveProcesses)
+	values["idle"] = float64(fpm.IdleProcesses)
+	values["total"] = float64(fpm.TotalProcesses)
+	if err := collectMap(ch, phpFPMProcessesDesc, values); err != nil {
+		return err
+	}
+
+	metric, err := prometheus.NewConstMetric(phpFPMMaxChildrenReachedDesc, prometheus.CounterValue, float64(fpm.MaxChildrenReached))
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", phpFPMMaxChildrenReachedDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
+func collectOPcache(ch chan<- prometheus.Metric, opcache *serverinfo.OPcache) error {
+	if opcache == nil {
+		return nil
+	}
+
+	restarts := map[string]uint{
+		"oom":    opcache.Statistics.OOMRestarts,
+		"hash":   opcache.Statistics.HashRestarts,
+		"manual": opcache.Statistics.ManualRestarts,
+	}
+	for reason, count := range restarts {
+		metric, err := prometheus.NewConstMetric(phpOPcacheRestartsDesc, prometheus.CounterValue, float64(count), reason)
+		if err != nil {
+			return fmt.Errorf("error creating metric for %s: %w", phpOPcacheRestartsDesc, err)
+		}
+		ch <- metric
+	}
+
+	return collectHitRate(ch, phpOPcacheHitRateDesc, opcache.Statistics.Hits, opcache.Statistics.Misses)
+}
+
+// collectHitRate emits the ratio of hits to all accesses of a cache. The ratio is zero if the cache was not accessed yet.
+func collectHitRate(ch chan<- prometheus.Metric, desc *prometheus.Desc, hits, misses uint64) error {
+	rate := 0.0
+	if total := hits + misses; total > 0 {
+		rate = float64(hits) / float64(total)
+	}
+
+	metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, rate)
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", desc, err)
+	}
+	ch <- metric
+
+	return nil
+}
+
 func collectMap(ch chan<- prometheus.Metric, desc *prometheus.Desc, labelValueMap map[string]float64) error {
 	for k, v := range labelValueMap {
 		metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, v, k)
 		if err != nil {
-			return fmt.Errorf("error creating shares metric for %s: %w", k, err)
+			return fmt.Errorf("error creating metric for %s with label %q: %w", desc, k, err)
 		}
 		ch <- metric
 	}
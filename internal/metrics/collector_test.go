@@ -0,0 +1,446 @@
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/xperimental/nextcloud-exporter/internal/client"
+	"github.com/xperimental/nextcloud-exporter/serverinfo"
+)
+
+func TestErrorCause(t *testing.T) {
+	tt := []struct {
+		desc      string
+		err       error
+		wantCause string
+	}{
+		{
+			desc:      "auth",
+			err:       client.ErrNotAuthorized,
+			wantCause: labelErrorCauseAuth,
+		},
+		{
+			desc:      "truncated",
+			err:       client.ErrTruncatedResponse,
+			wantCause: labelErrorCauseTruncated,
+		},
+		{
+			desc:      "status",
+			err:       &client.HTTPStatusError{Code: 503},
+			wantCause: labelErrorCauseStatus,
+		},
+		{
+			desc:      "status in precheck",
+			err:       fmt.Errorf("precheck failed: %w", &client.HTTPStatusError{Code: 503}),
+			wantCause: labelErrorCauseStatus,
+		},
+		{
+			desc:      "parse",
+			err:       &client.ParseError{Inner: errors.New("invalid character")},
+			wantCause: labelErrorCauseParse,
+		},
+		{
+			desc:      "connection",
+			err:       &client.ConnectionError{Inner: errors.New("connection refused")},
+			wantCause: labelErrorCauseConnection,
+		},
+		{
+			desc:      "too large",
+			err:       client.ResponseTooLargeError{Limit: 1024},
+			wantCause: labelErrorCauseTooLarge,
+		},
+		{
+			desc:      "ocs status",
+			err:       &serverinfo.OCSError{StatusCode: 998},
+			wantCause: labelErrorCauseOCS,
+		},
+		{
+			desc:      "other",
+			err:       errors.New("test error"),
+			wantCause: labelErrorCauseOther,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorCause(tc.err); got != tc.wantCause {
+				t.Errorf("got cause %q, want %q", got, tc.wantCause)
+			}
+		})
+	}
+}
+
+type collectorFunc func(ch chan<- prometheus.Metric)
+
+func (f collectorFunc) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(f, ch)
+}
+
+func (f collectorFunc) Collect(ch chan<- prometheus.Metric) {
+	f(ch)
+}
+
+func TestCollectPHPSettings(t *testing.T) {
+	settings := map[string]interface{}{
+		"max_execution_time": float64(3600),
+		"output_buffering":   false,
+		"session_lifetime":   "1440",
+		"memory_limit":       "512M",
+	}
+	names := []string{"max_execution_time", "output_buffering", "session_lifetime", "memory_limit", "missing"}
+
+	collector := collectorFunc(func(ch chan<- prometheus.Metric) {
+		if err := collectPHPSettings(ch, settings, names); err != nil {
+			t.Errorf("got error %q", err)
+		}
+	})
+
+	want := `# HELP php_setting Value of a configured PHP setting. Booleans are exported as 0 or 1.
+# TYPE php_setting gauge
+php_setting{name="max_execution_time"} 3600
+php_setting{name="output_buffering"} 0
+php_setting{name="session_lifetime"} 1440
+# HELP php_setting_info Contains the value of a configured PHP setting, which is not a number, as label. Value is always 1.
+# TYPE php_setting_info gauge
+php_setting_info{name="memory_limit",value="512M"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectHTTPSEnforced(t *testing.T) {
+	tt := []struct {
+		desc string
+		res  *client.Response
+		want string
+	}{
+		{
+			desc: "not read using HTTP",
+			res:  &client.Response{},
+			want: "",
+		},
+		{
+			desc: "http",
+			res: &client.Response{
+				Header: http.Header{
+					headerHSTS: []string{"max-age=15552000"},
+				},
+			},
+			want: `# HELP https_enforced Indicates if the server info was served using HTTPS with a Strict-Transport-Security header.
+# TYPE https_enforced gauge
+https_enforced 0
+`,
+		},
+		{
+			desc: "https without hsts",
+			res: &client.Response{
+				Header: http.Header{},
+				TLS:    &tls.ConnectionState{},
+			},
+			want: `# HELP https_enforced Indicates if the server info was served using HTTPS with a Strict-Transport-Security header.
+# TYPE https_enforced gauge
+https_enforced 0
+`,
+		},
+		{
+			desc: "https with hsts",
+			res: &client.Response{
+				Header: http.Header{
+					headerHSTS: []string{"max-age=15552000"},
+				},
+				TLS: &tls.ConnectionState{},
+			},
+			want: `# HELP https_enforced Indicates if the server info was served using HTTPS with a Strict-Transport-Security header.
+# TYPE https_enforced gauge
+https_enforced 1
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			collector := collectorFunc(func(ch chan<- prometheus.Metric) {
+				if err := collectResponseMetrics(ch, tc.res); err != nil {
+					t.Errorf("got error %q", err)
+				}
+			})
+
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tc.want), "https_enforced"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectClockSkew(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		desc string
+		date string
+		want string
+	}{
+		{
+			desc: "no date",
+			date: "",
+			want: "",
+		},
+		{
+			desc: "invalid date",
+			date: "yesterday",
+			want: "",
+		},
+		{
+			desc: "in sync",
+			date: now.Format(http.TimeFormat),
+			want: `# HELP clock_skew_seconds Difference between the time reported by the server and the time of the exporter in seconds. Positive values mean the server clock is ahead.
+# TYPE clock_skew_seconds gauge
+clock_skew_seconds 0
+`,
+		},
+		{
+			desc: "server ahead",
+			date: now.Add(30 * time.Second).Format(http.TimeFormat),
+			want: `# HELP clock_skew_seconds Difference between the time reported by the server and the time of the exporter in seconds. Positive values mean the server clock is ahead.
+# TYPE clock_skew_seconds gauge
+clock_skew_seconds 30
+`,
+		},
+		{
+			desc: "server behind",
+			date: now.Add(-90 * time.Second).Format(http.TimeFormat),
+			want: `# HELP clock_skew_seconds Difference between the time reported by the server and the time of the exporter in seconds. Positive values mean the server clock is ahead.
+# TYPE clock_skew_seconds gauge
+clock_skew_seconds -90
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			res := &client.Response{
+				Header:     http.Header{},
+				ReceivedAt: now,
+			}
+			if tc.date != "" {
+				res.Header.Set(headerDate, tc.date)
+			}
+
+			collector := collectorFunc(func(ch chan<- prometheus.Metric) {
+				if err := collectResponseMetrics(ch, res); err != nil {
+					t.Errorf("got error %q", err)
+				}
+			})
+
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tc.want), "clock_skew_seconds"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectActiveUsers(t *testing.T) {
+	tt := []struct {
+		desc      string
+		inputFile string
+		want      string
+	}{
+		{
+			desc:      "without periods",
+			inputFile: "nc22.json",
+			want: `# HELP active_users_daily_total Number of active users for the last 24 hours.
+# TYPE active_users_daily_total gauge
+active_users_daily_total 3
+# HELP active_users_hourly_total Number of active users for the last hour.
+# TYPE active_users_hourly_total gauge
+active_users_hourly_total 3
+# HELP active_users_total Number of active users for the last five minutes.
+# TYPE active_users_total gauge
+active_users_total 3
+`,
+		},
+		{
+			desc:      "with periods",
+			inputFile: "nc28.json",
+			want: `# HELP active_users_daily_total Number of active users for the last 24 hours.
+# TYPE active_users_daily_total gauge
+active_users_daily_total 3
+# HELP active_users_hourly_total Number of active users for the last hour.
+# TYPE active_users_hourly_total gauge
+active_users_hourly_total 3
+# HELP active_users_period_total Number of active users for periods longer than a day. Only reported by newer versions of the serverinfo app.
+# TYPE active_users_period_total gauge
+active_users_period_total{period="last1month"} 5
+active_users_period_total{period="last3months"} 5
+active_users_period_total{period="last6months"} 6
+active_users_period_total{period="last7days"} 4
+active_users_period_total{period="lastyear"} 7
+# HELP active_users_total Number of active users for the last five minutes.
+# TYPE active_users_total gauge
+active_users_total 2
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reader, err := os.Open("../../serverinfo/testdata/" + tc.inputFile)
+			if err != nil {
+				t.Fatalf("error opening test data: %s", err)
+			}
+			defer reader.Close()
+
+			info, err := serverinfo.ParseJSON(reader)
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			collector := collectorFunc(func(ch chan<- prometheus.Metric) {
+				if err := collectActiveUsers(ch, info.Data.ActiveUsers); err != nil {
+					t.Errorf("got error %q", err)
+				}
+			})
+
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tc.want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectFPM(t *testing.T) {
+	fpm := &serverinfo.FPM{
+		IdleProcesses:      3,
+		ActiveProcesses:    2,
+		TotalProcesses:     5,
+		MaxChildrenReached: 1,
+	}
+
+	collector := collectorFunc(func(ch chan<- prometheus.Metric) {
+		if err := collectFPM(ch, fpm); err != nil {
+			t.Errorf("got error %q", err)
+		}
+	})
+
+	want := `# HELP php_fpm_max_children_reached_total Number of times the PHP-FPM process limit has been reached.
+# TYPE php_fpm_max_children_reached_total counter
+php_fpm_max_children_reached_total 1
+# HELP php_fpm_processes Number of PHP-FPM processes by state. The total state contains all processes of the pool.
+# TYPE php_fpm_processes gauge
+php_fpm_processes{state="active"} 2
+php_fpm_processes{state="idle"} 3
+php_fpm_processes{state="total"} 5
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLastScrapeSuccess(t *testing.T) {
+	var (
+		fail   bool
+		leader = true
+	)
+	infoClient := func(ctx context.Context) (*client.Response, error) {
+		if fail {
+			return nil, &client.HTTPStatusError{Code: http.StatusServiceUnavailable}
+		}
+
+		return &client.Response{
+			Info: &serverinfo.ServerInfo{},
+		}, nil
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := RegisterCollector(registry, logrus.New(), infoClient, CollectorOptions{
+		IsLeader: func() bool {
+			return leader
+		},
+	}); err != nil {
+		t.Fatalf("got error registering collector: %s", err)
+	}
+
+	gather := func() (up, lastSuccess, fromCache float64) {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("got error gathering metrics: %s", err)
+		}
+
+		for _, family := range families {
+			switch family.GetName() {
+			case "up":
+				up = family.GetMetric()[0].GetGauge().GetValue()
+			case "last_scrape_success_timestamp_seconds":
+				lastSuccess = family.GetMetric()[0].GetGauge().GetValue()
+			case "exporter_served_from_cache":
+				fromCache = family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		return up, lastSuccess, fromCache
+	}
+
+	before := float64(time.Now().Unix())
+	up, success, fromCache := gather()
+	if up != 1 || success < before || fromCache != 0 {
+		t.Fatalf("got up %v, last success %v and served from cache %v after successful scrape, want 1, at least %v and 0", up, success, fromCache, before)
+	}
+
+	fail = true
+	if up, got, _ := gather(); up != 0 || got != success {
+		t.Errorf("got up %v and last success %v after failed scrape, want 0 and %v", up, got, success)
+	}
+
+	fail = false
+	leader = false
+	if _, got, fromCache := gather(); got != success || fromCache != 1 {
+		t.Errorf("got last success %v and served from cache %v while not leader, want %v and 1", got, fromCache, success)
+	}
+}
+
+func TestCollectOCSError(t *testing.T) {
+	infoClient := func(ctx context.Context) (*client.Response, error) {
+		return nil, &serverinfo.OCSError{StatusCode: 998, Message: "Invalid query"}
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := RegisterCollector(registry, logrus.New(), infoClient, CollectorOptions{}); err != nil {
+		t.Fatalf("got error registering collector: %s", err)
+	}
+
+	want := `# HELP last_ocs_status_code Status code contained in the OCS meta information of the last server info response.
+# TYPE last_ocs_status_code gauge
+last_ocs_status_code 998
+# HELP scrape_errors_total Counts the number of scrape errors by this collector.
+# TYPE scrape_errors_total counter
+scrape_errors_total{cause="ocs"} 1
+# HELP up Indicates if the metrics could be scraped by the exporter.
+# TYPE up gauge
+up 0
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "last_ocs_status_code", "scrape_errors_total", "up"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const eolDateFormat = "2006-01-02"
+
+// defaultPHPEndOfLife contains the end of security support for PHP versions as published on php.net.
+var defaultPHPEndOfLife = map[string]string{
+	"5.6": "2018-12-31",
+	"7.0": "2019-01-10",
+	"7.1": "2019-12-01",
+	"7.2": "2020-11-30",
+	"7.3": "2021-12-06",
+	"7.4": "2022-11-28",
+	"8.0": "2023-11-26",
+	"8.1": "2025-12-31",
+	"8.2": "2026-12-31",
+	"8.3": "2027-12-31",
+	"8.4": "2028-12-31",
+}
+
+// parseEndOfLife merges the custom end-of-life dates with the defaults and parses the result.
+func parseEndOfLife(custom map[string]string) (map[string]time.Time, error) {
+	merged := make(map[string]string, len(defaultPHPEndOfLife)+len(custom))
+	for version, date := range defaultPHPEndOfLife {
+		merged[version] = date
+	}
+	for version, date := range custom {
+		merged[version] = date
+	}
+
+	result := make(map[string]time.Time, len(merged))
+	for version, date := range merged {
+		parsed, err := time.Parse(eolDateFormat, date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end-of-life date for PHP %s: %q", version, date)
+		}
+
+		result[version] = parsed
+	}
+
+	return result, nil
+}
+
+// minorVersion returns the "major.minor" part of a version string.
+func minorVersion(version string) string {
+	tokens := strings.SplitN(version, ".", 3)
+	if len(tokens) < 2 {
+		return version
+	}
+
+	return tokens[0] + "." + tokens[1]
+}
@@ -1,12 +1,14 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func RegisterInfoMetric(version, gitCommit string) error {
+func RegisterInfoMetric(registerer prometheus.Registerer, version, gitCommit string) error {
 	infoMetric := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: metricPrefix + "exporter_info",
+		Name: "exporter_info",
 		Help: "Information about the nextcloud-exporter.",
 		ConstLabels: prometheus.Labels{
 			"version": version,
@@ -15,5 +17,31 @@ func RegisterInfoMetric(version, gitCommit string) error {
 	})
 	infoMetric.Set(1)
 
-	return prometheus.Register(infoMetric)
+	return registerer.Register(infoMetric)
+}
+
+// RegisterStartTimeMetric registers a metric containing the time the exporter was started.
+func RegisterStartTimeMetric(registerer prometheus.Registerer, startTime time.Time) error {
+	startTimeMetric := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "exporter_start_time_seconds",
+		Help: "Start time of the nextcloud-exporter as seconds since the Unix epoch.",
+	})
+	startTimeMetric.Set(float64(startTime.UnixNano()) / 1e9)
+
+	return registerer.Register(startTimeMetric)
+}
+
+// RegisterLeaderMetric registers a metric showing if the exporter currently is the leader.
+func RegisterLeaderMetric(registerer prometheus.Registerer, isLeader func() bool) error {
+	leaderMetric := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "exporter_is_leader",
+		Help: "Indicates if this instance of the exporter is the leader and queries the server.",
+	}, func() float64 {
+		if isLeader() {
+			return 1
+		}
+		return 0
+	})
+
+	return registerer.Register(leaderMetric)
 }
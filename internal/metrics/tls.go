@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tlsVersionInfoDesc = prometheus.NewDesc(
+		"tls_version_info",
+		"Contains the TLS version and cipher suite of the connection to the server as labels. Value is always 1.",
+		[]string{"version", "cipher"}, nil)
+	tlsCertExpiryDesc = prometheus.NewDesc(
+		"tls_cert_expiry_timestamp_seconds",
+		"Time when the certificate of the server expires as seconds since the Unix epoch.",
+		nil, nil)
+	tlsCertValidDesc = prometheus.NewDesc(
+		"tls_cert_valid",
+		"Indicates if the certificate of the server is trusted, even if the verification is disabled. The error label contains the reason if it is not.",
+		[]string{"error"}, nil)
+
+	tlsVersionNames = map[uint16]string{
+		tls.VersionTLS10: "TLS 1.0",
+		tls.VersionTLS11: "TLS 1.1",
+		tls.VersionTLS12: "TLS 1.2",
+		tls.VersionTLS13: "TLS 1.3",
+	}
+)
+
+// tlsVersionName returns a name like "TLS 1.3" for the version or the hexadecimal value for unknown versions.
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// Values of the error label of the certificate validity.
+const (
+	certErrorNone             = ""
+	certErrorExpired          = "expired"
+	certErrorUnknownAuthority = "unknown_authority"
+	certErrorHostnameMismatch = "hostname_mismatch"
+	certErrorInvalid          = "invalid"
+	certErrorOther            = "other"
+)
+
+// certErrorReason returns a fixed reason for a verification error, because the error messages contain details like
+// the current time, which would create a new label value on every scrape.
+func certErrorReason(err error) string {
+	var (
+		invalidErr   x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+	)
+
+	switch {
+	case err == nil:
+		return certErrorNone
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return certErrorExpired
+	case errors.As(err, &invalidErr):
+		return certErrorInvalid
+	case errors.As(err, &authorityErr):
+		return certErrorUnknownAuthority
+	case errors.As(err, &hostnameErr):
+		return certErrorHostnameMismatch
+	default:
+		return certErrorOther
+	}
+}
+
+func collectTLSMetrics(ch chan<- prometheus.Metric, state *tls.ConnectionState, certErr error) error {
+	tlsInfo := []string{
+		tlsVersionName(state.Version),
+		tls.CipherSuiteName(state.CipherSuite),
+	}
+	if err := collectInfoMetric(ch, tlsVersionInfoDesc, tlsInfo); err != nil {
+		return err
+	}
+
+	if len(state.PeerCertificates) > 0 {
+		// the first certificate is the one of the server, the others belong to the chain
+		expiry := state.PeerCertificates[0].NotAfter
+		metric, err := prometheus.NewConstMetric(tlsCertExpiryDesc, prometheus.GaugeValue, float64(expiry.Unix()))
+		if err != nil {
+			return fmt.Errorf("error creating metric for %s: %w", tlsCertExpiryDesc, err)
+		}
+		ch <- metric
+	}
+
+	valid := 0.0
+	if certErr == nil {
+		valid = 1
+	}
+
+	metric, err := prometheus.NewConstMetric(tlsCertValidDesc, prometheus.GaugeValue, valid, certErrorReason(certErr))
+	if err != nil {
+		return fmt.Errorf("error creating metric for %s: %w", tlsCertValidDesc, err)
+	}
+	ch <- metric
+
+	return nil
+}
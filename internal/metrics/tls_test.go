@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+)
+
+func TestTLSVersionName(t *testing.T) {
+	tt := []struct {
+		version  uint16
+		wantName string
+	}{
+		{
+			version:  tls.VersionTLS12,
+			wantName: "TLS 1.2",
+		},
+		{
+			version:  tls.VersionTLS13,
+			wantName: "TLS 1.3",
+		},
+		{
+			version:  0x0305,
+			wantName: "0x0305",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.wantName, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tlsVersionName(tc.version); got != tc.wantName {
+				t.Errorf("got name %q, want %q", got, tc.wantName)
+			}
+		})
+	}
+}
+
+func TestCertErrorReason(t *testing.T) {
+	tt := []struct {
+		desc       string
+		err        error
+		wantReason string
+	}{
+		{
+			desc:       "valid",
+			err:        nil,
+			wantReason: certErrorNone,
+		},
+		{
+			desc:       "expired",
+			err:        x509.CertificateInvalidError{Reason: x509.Expired},
+			wantReason: certErrorExpired,
+		},
+		{
+			desc:       "invalid",
+			err:        x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign},
+			wantReason: certErrorInvalid,
+		},
+		{
+			desc:       "unknown authority",
+			err:        x509.UnknownAuthorityError{},
+			wantReason: certErrorUnknownAuthority,
+		},
+		{
+			desc:       "hostname mismatch",
+			err:        x509.HostnameError{Host: "example.com"},
+			wantReason: certErrorHostnameMismatch,
+		},
+		{
+			desc:       "other",
+			err:        errors.New("other error"),
+			wantReason: certErrorOther,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := certErrorReason(tc.err); got != tc.wantReason {
+				t.Errorf("got reason %q, want %q", got, tc.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	labelMajorVersion   = "major_version"
+	unknownMajorVersion = "unknown"
+)
+
+// majorVersion returns the major version from a version string like "27.1.4.2" or "unknown" if the version
+// can not be parsed.
+func majorVersion(version string) string {
+	tokens := strings.SplitN(strings.TrimSpace(version), ".", 2)
+	major, err := strconv.Atoi(tokens[0])
+	if err != nil || major < 0 {
+		return unknownMajorVersion
+	}
+
+	return strconv.Itoa(major)
+}
+
+// versionTracker remembers the major version of the last successful scrape, so that metrics of failed scrapes
+// keep their labels.
+type versionTracker struct {
+	mu      sync.Mutex
+	version string
+}
+
+func newVersionTracker() *versionTracker {
+	return &versionTracker{
+		version: unknownMajorVersion,
+	}
+}
+
+func (t *versionTracker) set(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.version = majorVersion(version)
+}
+
+func (t *versionTracker) get() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.version
+}
+
+// captureRegisterer keeps the last collector registered with it instead of registering it.
+type captureRegisterer struct {
+	collector prometheus.Collector
+}
+
+func (r *captureRegisterer) Register(c prometheus.Collector) error {
+	r.collector = c
+	return nil
+}
+
+func (r *captureRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		_ = r.Register(c)
+	}
+}
+
+func (r *captureRegisterer) Unregister(prometheus.Collector) bool {
+	return false
+}
+
+// collectWithLabels adds the labels to all metrics of the collector. The labels are known only after
+// collecting, so the metrics are buffered and then emitted using the wrapping of the prometheus package.
+func collectWithLabels(ch chan<- prometheus.Metric, labels func() prometheus.Labels, collect func(chan<- prometheus.Metric)) {
+	metrics := bufferMetrics(collect)
+
+	capture := &captureRegisterer{}
+	prometheus.WrapRegistererWith(labels(), capture).MustRegister(metrics)
+	capture.collector.Collect(ch)
+}
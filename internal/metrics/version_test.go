@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMajorVersion(t *testing.T) {
+	tt := []struct {
+		version string
+		want    string
+	}{
+		{
+			version: "27.1.4.2",
+			want:    "27",
+		},
+		{
+			version: "21.0.3.1",
+			want:    "21",
+		},
+		{
+			version: "28",
+			want:    "28",
+		},
+		{
+			version: " 26.0.0 ",
+			want:    "26",
+		},
+		{
+			version: "",
+			want:    "unknown",
+		},
+		{
+			version: "v27.1",
+			want:    "unknown",
+		},
+		{
+			version: "-1.0",
+			want:    "unknown",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.version, func(t *testing.T) {
+			t.Parallel()
+
+			got := majorVersion(tc.version)
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCollectWithLabels(t *testing.T) {
+	desc := prometheus.NewDesc("test_metric", "Test metric.", nil, nil)
+	ch := make(chan prometheus.Metric, 10)
+
+	collectWithLabels(ch, func() prometheus.Labels {
+		return prometheus.Labels{
+			labelMajorVersion: "27",
+		}
+	}, func(ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 2)
+	})
+	close(ch)
+
+	count := 0
+	for metric := range ch {
+		count++
+		got := metric.Desc().String()
+		if !strings.Contains(got, `major_version="27"`) {
+			t.Errorf("got desc %s without version label", got)
+		}
+	}
+
+	if count != 2 {
+		t.Errorf("got %d metrics, want 2", count)
+	}
+}
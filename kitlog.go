@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// kitLogger forwards messages logged using the go-kit interface, as used by the exporter-toolkit, to logrus.
+type kitLogger struct {
+	log logrus.FieldLogger
+}
+
+func (l kitLogger) Log(keyvals ...interface{}) error {
+	var level, msg string
+	fields := logrus.Fields{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "level":
+			level = fmt.Sprint(keyvals[i+1])
+		case "msg":
+			msg = fmt.Sprint(keyvals[i+1])
+		default:
+			fields[key] = keyvals[i+1]
+		}
+	}
+
+	entry := l.log.WithFields(fields)
+	switch level {
+	case "debug":
+		entry.Debug(msg)
+	case "warn":
+		entry.Warn(msg)
+	case "error":
+		entry.Error(msg)
+	default:
+		entry.Info(msg)
+	}
+
+	return nil
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/sirupsen/logrus"
 	"github.com/xperimental/nextcloud-exporter/internal/client"
 	"github.com/xperimental/nextcloud-exporter/internal/config"
+	"github.com/xperimental/nextcloud-exporter/internal/leader"
 	"github.com/xperimental/nextcloud-exporter/internal/login"
 	"github.com/xperimental/nextcloud-exporter/internal/metrics"
 	"github.com/xperimental/nextcloud-exporter/serverinfo"
@@ -34,6 +42,8 @@ var (
 )
 
 func main() {
+	startTime := time.Now()
+
 	cfg, err := config.Get()
 	if err != nil {
 		log.Fatalf("Error loading configuration: %s", err)
@@ -68,30 +78,286 @@ func main() {
 		log.Fatalf("Invalid configuration: %s", err)
 	}
 
+	infoClient, webdavCheck, reachabilityCheck := createClients(cfg, userAgent)
+	if cfg.RunMode == config.RunModeCheck {
+		res, err := infoClient(context.Background())
+		if err != nil {
+			log.Fatalf("Check failed: %s", err)
+		}
+
+		version := res.Info.Data.Nextcloud.System.Version
+		if cfg.MinVersion != "" {
+			if err := checkMinVersion(version, cfg.MinVersion); err != nil {
+				log.Fatalf("Check failed: %s", err)
+			}
+		}
+
+		log.Infof("Check successful. Nextcloud version: %s", version)
+		return
+	}
+
+	if cfg.MinVersion != "" && cfg.RunMode == config.RunModeExporter {
+		go func() {
+			res, err := infoClient(context.Background())
+			if err != nil {
+				log.Warnf("Could not check version of server: %s", err)
+				return
+			}
+
+			if err := checkMinVersion(res.Info.Data.Nextcloud.System.Version, cfg.MinVersion); err != nil {
+				if cfg.MinVersionExit {
+					log.Fatal(err)
+				}
+				log.Error(err)
+			}
+		}()
+	}
+
+	var versionErr func() error
+	if cfg.MinVersion != "" && cfg.RunMode == config.RunModeOnce {
+		// check the version in the response used for the metrics instead of sending another request
+		infoClient, versionErr = versionCheckClient(infoClient, cfg.MinVersion)
+	}
+
+	registry := prometheus.NewRegistry()
+	labelRegisterer := prometheus.WrapRegistererWith(cfg.Labels, registry)
+	if err := registerRuntimeCollectors(labelRegisterer); err != nil {
+		log.Fatalf("Failed to register runtime collectors: %s", err)
+	}
+
+	registerer := prometheus.WrapRegistererWithPrefix(cfg.MetricsPrefix, labelRegisterer)
+	collectorOpts := metrics.CollectorOptions{
+		CircuitBreakerThreshold: cfg.CircuitBreakerThreshold,
+		CircuitBreakerCooldown:  cfg.CircuitBreakerCooldown,
+		PHPEndOfLife:            cfg.PHPEndOfLife,
+		PHPSettings:             cfg.PHPSettings,
+		ScrapeDurationBuckets:   cfg.ScrapeDurationBuckets,
+		CollectTimeout:          cfg.CollectTimeout,
+		MajorVersionLabel:       cfg.MajorVersionLabel,
+		CompatMetrics:           cfg.CompatMetrics,
+		WebDAVCheck:             webdavCheck,
+		ReachabilityCheck:       reachabilityCheck,
+	}
+	if cfg.RunMode == config.RunModeExporter {
+		collectorOpts.ScrapeInterval = cfg.ScrapeInterval
+	}
+
+	if cfg.LeaderLockFile != "" && cfg.RunMode == config.RunModeExporter {
+		lease := createLease(cfg)
+		go lease.Run()
+
+		collectorOpts.IsLeader = lease.IsLeader
+		if err := metrics.RegisterLeaderMetric(registerer, lease.IsLeader); err != nil {
+			log.Fatalf("Failed to register leader metric: %s", err)
+		}
+	}
+
+	if err := metrics.RegisterCollector(registerer, log, infoClient, collectorOpts); err != nil {
+		log.Fatalf("Failed to register collector: %s", err)
+	}
+
+	if err := metrics.RegisterInfoMetric(registerer, Version, GitCommit); err != nil {
+		log.Fatalf("Failed to register info metric: %s", err)
+	}
+
+	if err := metrics.RegisterStartTimeMetric(registerer, startTime); err != nil {
+		log.Fatalf("Failed to register start time metric: %s", err)
+	}
+
+	if cfg.RunMode == config.RunModeOnce {
+		if err := writeMetrics(registry, cfg.OutputFile); err != nil {
+			log.Fatalf("Failed to write metrics: %s", err)
+		}
+
+		if versionErr != nil {
+			if err := versionErr(); err != nil {
+				if cfg.MinVersionExit {
+					log.Fatal(err)
+				}
+				log.Error(err)
+			}
+		}
+		return
+	}
+
+	if cfg.PushURL != "" {
+		log.Infof("Pushing metrics to %s every %s...", cfg.PushURL, cfg.PushInterval)
+		go runPush(registry, cfg.PushURL, cfg.PushJob, cfg.PushLabels, cfg.PushInterval)
+	}
+
+	metricsPath := cfg.RoutePrefix() + "/metrics"
+	http.Handle(metricsPath, promhttp.InstrumentMetricHandler(labelRegisterer, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	http.Handle("/", http.RedirectHandler(metricsPath, http.StatusFound))
+
+	log.Infof("Listen on %s...", cfg.ListenAddr)
+	server := &http.Server{
+		Addr: cfg.ListenAddr,
+	}
+	log.Fatal(web.ListenAndServe(server, cfg.WebConfigFile, kitLogger{log}))
+}
+
+// versionCheckClient wraps the client and checks the version of the server in every successful response.
+// The returned function returns the result of the last check.
+func versionCheckClient(infoClient client.InfoClient, minVersion string) (client.InfoClient, func() error) {
+	var (
+		mu  sync.Mutex
+		err error
+	)
+	wrapped := func(ctx context.Context) (*client.Response, error) {
+		res, resErr := infoClient(ctx)
+		if resErr != nil {
+			return res, resErr
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		err = checkMinVersion(res.Info.Data.Nextcloud.System.Version, minVersion)
+		return res, nil
+	}
+	result := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return err
+	}
+	return wrapped, result
+}
+
+// checkMinVersion returns an error if the version of the server is older than the minimum version.
+func checkMinVersion(version, minVersion string) error {
+	required, err := serverinfo.ParseVersion(minVersion)
+	if err != nil {
+		return fmt.Errorf("can not parse minimum version: %w", err)
+	}
+
+	current, err := serverinfo.ParseVersion(version)
+	if err != nil {
+		return fmt.Errorf("can not parse version of server: %w", err)
+	}
+
+	if current.Less(required) {
+		return fmt.Errorf("version %s of server is older than minimum version %s", version, minVersion)
+	}
+
+	return nil
+}
+
+// registerRuntimeCollectors registers the collectors for the metrics about the Go runtime and the process of the
+// exporter, which are contained in the default registry of the Prometheus client.
+func registerRuntimeCollectors(registerer prometheus.Registerer) error {
+	if err := registerer.Register(prometheus.NewGoCollector()); err != nil {
+		return fmt.Errorf("can not register Go collector: %w", err)
+	}
+
+	if err := registerer.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})); err != nil {
+		return fmt.Errorf("can not register process collector: %w", err)
+	}
+
+	return nil
+}
+
+func createLease(cfg config.Config) *leader.Lease {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Failed to get hostname for leader election: %s", err)
+	}
+
+	id := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	log.Infof("Using leader lock file %s as %s.", cfg.LeaderLockFile, id)
+	return leader.New(log, cfg.LeaderLockFile, id, cfg.LeaderLeaseDuration)
+}
+
+func createClients(cfg config.Config, userAgent string) (client.InfoClient, client.WebDAVCheck, client.ReachabilityCheck) {
+	if infoFile := cfg.InfoFile(); infoFile != "" {
+		log.Infof("Reading server info from file: %s", infoFile)
+		return client.NewFile(infoFile), nil, nil
+	}
+
 	if cfg.AuthToken == "" {
 		log.Infof("Nextcloud server: %s User: %s", cfg.ServerURL, cfg.Username)
 	} else {
 		log.Infof("Nextcloud server: %s Authentication using token.", cfg.ServerURL)
 	}
 
-	infoURL := cfg.ServerURL + serverinfo.InfoPath
+	if cfg.RunMode == config.RunModeCheck {
+		// the check reports the result of exactly one request
+		cfg.AuthFallbackBasic = false
+		cfg.RetryTruncated = false
+		cfg.HeadPrecheck = false
+		cfg.SecondaryInfoPath = ""
+	}
+
+	infoURL := cfg.ServerURL + serverinfo.InfoPath(cfg.APIVersion)
+
+	var secondaryInfoURL string
+	if cfg.SecondaryInfoPath != "" {
+		secondaryInfoURL = cfg.ServerURL + cfg.SecondaryInfoPath
+	}
 
 	if cfg.TLSSkipVerify {
 		log.Warn("HTTPS certificate verification is disabled.")
 	}
 
-	infoClient := client.New(infoURL, cfg.Username, cfg.Password, cfg.AuthToken, cfg.Timeout, userAgent, cfg.TLSSkipVerify)
-	if err := metrics.RegisterCollector(log, infoClient); err != nil {
-		log.Fatalf("Failed to register collector: %s", err)
+	var rootCAs *x509.CertPool
+	if cfg.TLSCAFile != "" {
+		pool, err := client.LoadCertPool(cfg.TLSCAFile, cfg.TLSCAOnly)
+		if err != nil {
+			log.Fatalf("Failed to load CA certificates: %s", err)
+		}
+		rootCAs = pool
 	}
 
-	if err := metrics.RegisterInfoMetric(Version, GitCommit); err != nil {
-		log.Fatalf("Failed to register info metric: %s", err)
+	var proxyURL *url.URL
+	if cfg.Proxy != "" {
+		parsed, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			log.Fatalf("Failed to parse proxy URL: %s", err)
+		}
+		proxyURL = parsed
+	}
+
+	if cfg.AuthFallbackBasic {
+		log.Warn("Falling back to username and password if the authentication token is rejected.")
 	}
 
-	http.Handle("/metrics", promhttp.Handler())
-	http.Handle("/", http.RedirectHandler("/metrics", http.StatusFound))
+	opts := client.Options{
+		Log:               log,
+		InfoURL:           infoURL,
+		SecondaryInfoURL:  secondaryInfoURL,
+		Username:          cfg.Username,
+		Password:          cfg.Password,
+		AuthToken:         cfg.AuthToken,
+		AuthTokenQuery:    cfg.AuthTokenQuery,
+		AuthFallbackBasic: cfg.AuthFallbackBasic,
+		BasicAuthUsername: cfg.BasicAuthUsername,
+		BasicAuthPassword: cfg.BasicAuthPassword,
+		Timeout:           cfg.Timeout,
+		UserAgent:         userAgent,
+		TLSSkipVerify:     cfg.TLSSkipVerify,
+		RootCAs:           rootCAs,
+		DNSServer:         cfg.DNSServer,
+		Proxy:             proxyURL,
+		Trace:             cfg.HTTPTrace,
+		HeadPrecheck:      cfg.HeadPrecheck,
+		MaxResponseSize:   cfg.MaxResponseSize,
+		RecordFile:        cfg.RecordFile,
+		RecordRedact:      cfg.RecordRedact,
+		AcceptStatusCodes: cfg.AcceptStatusCodes,
+		RetryTruncated:    cfg.RetryTruncated,
+	}
 
-	log.Infof("Listen on %s...", cfg.ListenAddr)
-	log.Fatal(http.ListenAndServe(cfg.ListenAddr, nil))
+	var webdavCheck client.WebDAVCheck
+	if cfg.WebDAVCheck {
+		webdavCheck = client.NewWebDAVCheck(opts, cfg.ServerURL+client.WebDAVPath)
+	}
+
+	var reachabilityCheck client.ReachabilityCheck
+	if cfg.ReachabilityCheck {
+		check, err := client.NewReachabilityCheck(opts, cfg.ServerURL)
+		if err != nil {
+			log.Fatalf("Failed to create reachability check: %s", err)
+		}
+		reachabilityCheck = check
+	}
+
+	return client.New(opts), webdavCheck, reachabilityCheck
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xperimental/nextcloud-exporter/internal/client"
+	"github.com/xperimental/nextcloud-exporter/internal/config"
+	"github.com/xperimental/nextcloud-exporter/serverinfo"
+)
+
+func TestCreateClientsCheckMode(t *testing.T) {
+	tt := []struct {
+		desc         string
+		runMode      config.RunMode
+		truncate     bool
+		extra        bool
+		wantErr      error
+		wantRequests int32
+	}{
+		{
+			desc:         "exporter",
+			runMode:      config.RunModeExporter,
+			wantErr:      nil,
+			wantRequests: 2,
+		},
+		{
+			desc:         "check",
+			runMode:      config.RunModeCheck,
+			wantErr:      client.ErrNotAuthorized,
+			wantRequests: 1,
+		},
+		{
+			desc:         "exporter truncated",
+			runMode:      config.RunModeExporter,
+			truncate:     true,
+			wantErr:      nil,
+			wantRequests: 2,
+		},
+		{
+			desc:         "check truncated",
+			runMode:      config.RunModeCheck,
+			truncate:     true,
+			wantErr:      client.ErrTruncatedResponse,
+			wantRequests: 1,
+		},
+		{
+			desc:         "exporter additional requests",
+			runMode:      config.RunModeExporter,
+			extra:        true,
+			wantErr:      nil,
+			wantRequests: 3,
+		},
+		{
+			desc:         "check additional requests",
+			runMode:      config.RunModeCheck,
+			extra:        true,
+			wantErr:      nil,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count := atomic.AddInt32(&requests, 1)
+
+				if _, _, ok := r.BasicAuth(); !ok && !tc.truncate && !tc.extra {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				if tc.truncate && count == 1 {
+					fmt.Fprint(w, `{"ocs": {"data": {"nextcloud": {`)
+					return
+				}
+
+				fmt.Fprint(w, `{"ocs": {"data": {"nextcloud": {"system": {"version": "27.1.4.2"}}}}}`)
+			}))
+			defer server.Close()
+
+			var secondaryInfoPath string
+			if tc.extra {
+				secondaryInfoPath = "/secondary"
+			}
+
+			infoClient, _, _ := createClients(config.Config{
+				RunMode:           tc.runMode,
+				ServerURL:         server.URL,
+				Username:          "user",
+				Password:          "password",
+				AuthToken:         "auth-token",
+				AuthFallbackBasic: true,
+				RetryTruncated:    true,
+				HeadPrecheck:      tc.extra,
+				SecondaryInfoPath: secondaryInfoPath,
+				APIVersion:        serverinfo.DefaultAPIVersion,
+			}, "test")
+
+			_, err := infoClient(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if got := atomic.LoadInt32(&requests); got != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVersionCheckClient(t *testing.T) {
+	tt := []struct {
+		desc    string
+		version string
+		err     error
+		wantErr bool
+	}{
+		{
+			desc:    "recent version",
+			version: "27.1.4.2",
+		},
+		{
+			desc:    "older version",
+			version: "25.0.0.1",
+			wantErr: true,
+		},
+		{
+			desc: "request failed",
+			err:  client.ErrNotAuthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			infoClient, versionErr := versionCheckClient(func(ctx context.Context) (*client.Response, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+
+				info := &serverinfo.ServerInfo{}
+				info.Data.Nextcloud.System.Version = tc.version
+				return &client.Response{Info: info}, nil
+			}, "26")
+
+			if err := versionErr(); err != nil {
+				t.Errorf("got error %q before request", err)
+			}
+
+			if _, err := infoClient(context.Background()); !errors.Is(err, tc.err) {
+				t.Errorf("got error %q, want %q", err, tc.err)
+			}
+
+			if gotErr := versionErr() != nil; gotErr != tc.wantErr {
+				t.Errorf("got version error %v, want %v", versionErr(), tc.wantErr)
+			}
+		})
+	}
+}
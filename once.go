@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// writeMetrics collects the metrics once and writes them in the text exposition format to stdout or a file.
+// The file is replaced atomically, so that it can be read by the textfile collector of node_exporter at any time.
+func writeMetrics(gatherer prometheus.Gatherer, fileName string) error {
+	if fileName != "" {
+		return prometheus.WriteToTextfile(fileName, gatherer)
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	encoder := expfmt.NewEncoder(os.Stdout, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
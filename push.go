@@ -0,0 +1,27 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// runPush pushes the metrics to a Pushgateway once per interval. It does not return.
+func runPush(gatherer prometheus.Gatherer, url, job string, grouping map[string]string, interval time.Duration) {
+	pusher := push.New(url, job).Gatherer(gatherer)
+	for name, value := range grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := pusher.Push(); err != nil {
+			log.Errorf("Failed to push metrics: %s", err)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,35 @@
+package serverinfo
+
+// Merge adds the sections present in other to the data. Sections present in both are replaced by the
+// section of other.
+func (d *Data) Merge(other Data) {
+	if d.Present == nil {
+		d.Present = make(map[string]bool)
+	}
+
+	for _, section := range Sections {
+		if !other.Present[section] {
+			continue
+		}
+
+		switch section {
+		case SectionSystem:
+			d.Nextcloud.System = other.Nextcloud.System
+		case SectionStorage:
+			d.Nextcloud.Storage = other.Nextcloud.Storage
+		case SectionShares:
+			d.Nextcloud.Shares = other.Nextcloud.Shares
+		case SectionPHP:
+			d.Server.PHP = other.Server.PHP
+		case SectionDatabase:
+			d.Server.Database = other.Server.Database
+		case SectionActiveUsers:
+			d.ActiveUsers = other.ActiveUsers
+		}
+		d.Present[section] = true
+	}
+
+	if other.Server.Webserver != "" {
+		d.Server.Webserver = other.Server.Webserver
+	}
+}
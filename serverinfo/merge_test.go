@@ -0,0 +1,59 @@
+package serverinfo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMerge(t *testing.T) {
+	primary, err := ParseJSON(strings.NewReader(`{"ocs": {"data": {"nextcloud": {"system": {"version": "27.1.4.2"}, "storage": {"num_users": 4}}, "server": {"webserver": "nginx", "database": {"size": 1024}}}}}`))
+	if err != nil {
+		t.Fatalf("got error parsing primary: %q", err)
+	}
+
+	secondary, err := ParseJSON(strings.NewReader(`{"ocs": {"data": {"nextcloud": {"storage": {"num_users": 5}, "shares": {"num_shares": 3}}, "activeUsers": {"last5minutes": 2}}}}`))
+	if err != nil {
+		t.Fatalf("got error parsing secondary: %q", err)
+	}
+
+	data := primary.Data
+	data.Merge(secondary.Data)
+
+	wantPresent := map[string]bool{
+		SectionSystem:      true,
+		SectionStorage:     true,
+		SectionShares:      true,
+		SectionPHP:         false,
+		SectionDatabase:    true,
+		SectionActiveUsers: true,
+	}
+	if diff := cmp.Diff(data.Present, wantPresent); diff != "" {
+		t.Errorf("present sections differ: -got +want\n%s", diff)
+	}
+
+	if got, want := data.Nextcloud.System.Version, "27.1.4.2"; got != want {
+		t.Errorf("got version %q, want %q", got, want)
+	}
+
+	if got, want := data.Nextcloud.Storage.Users, uint(5); got != want {
+		t.Errorf("got %d users, want %d", got, want)
+	}
+
+	if got, want := data.Nextcloud.Shares.SharesTotal, uint(3); got != want {
+		t.Errorf("got %d shares, want %d", got, want)
+	}
+
+	if got, want := data.ActiveUsers.Last5Minutes, uint(2); got != want {
+		t.Errorf("got %d active users, want %d", got, want)
+	}
+
+	if got, want := data.Server.Database.Size, uint64(1024); got != want {
+		t.Errorf("got database size %d, want %d", got, want)
+	}
+
+	if got, want := data.Server.Webserver, "nginx"; got != want {
+		t.Errorf("got webserver %q, want %q", got, want)
+	}
+}
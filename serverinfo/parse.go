@@ -2,17 +2,55 @@ package serverinfo
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 )
 
+const metaStatusOK = "ok"
+
+// OCSError is returned when the OCS meta information of the response signals a failure.
+type OCSError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *OCSError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned OCS status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("server returned OCS status code %d: %s", e.StatusCode, e.Message)
+}
+
 // ParseJSON reads ServerInfo from a Reader in JSON format.
 func ParseJSON(r io.Reader) (*ServerInfo, error) {
 	result := struct {
-		ServerInfo ServerInfo `json:"ocs"`
+		OCS struct {
+			Meta Meta            `json:"meta"`
+			Data json.RawMessage `json:"data"`
+		} `json:"ocs"`
 	}{}
 	if err := json.NewDecoder(r).Decode(&result); err != nil {
 		return nil, err
 	}
 
-	return &result.ServerInfo, nil
+	meta := result.OCS.Meta
+	if meta.Status != "" && meta.Status != metaStatusOK {
+		// the data is usually empty in this case, so it is not parsed
+		return nil, &OCSError{
+			StatusCode: meta.StatusCode,
+			Message:    meta.Message,
+		}
+	}
+
+	info := &ServerInfo{
+		Meta: meta,
+	}
+	if len(result.OCS.Data) > 0 {
+		if err := json.Unmarshal(result.OCS.Data, &info.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
 }
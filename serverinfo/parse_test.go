@@ -1,8 +1,14 @@
 package serverinfo
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xperimental/nextcloud-exporter/internal/testutil"
 )
 
 func TestParseJSON(t *testing.T) {
@@ -11,6 +17,9 @@ func TestParseJSON(t *testing.T) {
 		"negative-space.json",
 		"na-values.json",
 		"nc22.json",
+		"nc28.json",
+		"php-size-strings.json",
+		"php-fpm.json",
 	}
 
 	for _, inputFile := range inputFiles {
@@ -29,3 +38,541 @@ func TestParseJSON(t *testing.T) {
 		})
 	}
 }
+
+// TestParseVersions checks the parsed values of responses captured from different versions of Nextcloud.
+// A case should be added for every captured response added to the test data.
+func TestParseVersions(t *testing.T) {
+	tt := []struct {
+		inputFile       string
+		wantVersion     string
+		wantUsers       uint
+		wantFiles       uint
+		wantShares      uint
+		wantDatabase    string
+		wantActiveUsers uint
+		wantPHPVersion  string
+	}{
+		{
+			inputFile:       "info.json",
+			wantVersion:     "21.0.3.1",
+			wantUsers:       4,
+			wantFiles:       148948,
+			wantShares:      10,
+			wantDatabase:    "mysql",
+			wantActiveUsers: 1,
+			wantPHPVersion:  "7.4.3",
+		},
+		{
+			inputFile:       "nc22.json",
+			wantVersion:     "22.2.0.2",
+			wantUsers:       3,
+			wantFiles:       412,
+			wantShares:      8,
+			wantDatabase:    "mysql",
+			wantActiveUsers: 3,
+			wantPHPVersion:  "7.4.0",
+		},
+		{
+			inputFile:       "nc28.json",
+			wantVersion:     "28.0.4.1",
+			wantUsers:       3,
+			wantFiles:       412,
+			wantShares:      8,
+			wantDatabase:    "mysql",
+			wantActiveUsers: 2,
+			wantPHPVersion:  "8.2.10",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.inputFile, func(t *testing.T) {
+			t.Parallel()
+
+			reader, err := os.Open("testdata/" + tc.inputFile)
+			if err != nil {
+				t.Fatalf("error opening test data: %s", err)
+			}
+			defer reader.Close()
+
+			info, err := ParseJSON(reader)
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			data := info.Data
+			if data.Nextcloud.System.Version != tc.wantVersion {
+				t.Errorf("got version %q, want %q", data.Nextcloud.System.Version, tc.wantVersion)
+			}
+
+			if data.Nextcloud.Storage.Users != tc.wantUsers {
+				t.Errorf("got %d users, want %d", data.Nextcloud.Storage.Users, tc.wantUsers)
+			}
+
+			if data.Nextcloud.Storage.Files != tc.wantFiles {
+				t.Errorf("got %d files, want %d", data.Nextcloud.Storage.Files, tc.wantFiles)
+			}
+
+			if data.Nextcloud.Shares.SharesTotal != tc.wantShares {
+				t.Errorf("got %d shares, want %d", data.Nextcloud.Shares.SharesTotal, tc.wantShares)
+			}
+
+			if data.Server.Database.Type != tc.wantDatabase {
+				t.Errorf("got database %q, want %q", data.Server.Database.Type, tc.wantDatabase)
+			}
+
+			if data.ActiveUsers.Last5Minutes != tc.wantActiveUsers {
+				t.Errorf("got %d active users, want %d", data.ActiveUsers.Last5Minutes, tc.wantActiveUsers)
+			}
+
+			if data.Server.PHP.Version != tc.wantPHPVersion {
+				t.Errorf("got PHP version %q, want %q", data.Server.PHP.Version, tc.wantPHPVersion)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tt := []struct {
+		desc     string
+		value    interface{}
+		wantErr  error
+		wantSize int64
+	}{
+		{
+			desc:     "number",
+			value:    float64(536870912),
+			wantSize: 536870912,
+		},
+		{
+			desc:     "string without unit",
+			value:    "2097152",
+			wantSize: 2097152,
+		},
+		{
+			desc:     "kilobytes",
+			value:    "128K",
+			wantSize: 128 * 1024,
+		},
+		{
+			desc:     "megabytes",
+			value:    "512M",
+			wantSize: 512 * 1024 * 1024,
+		},
+		{
+			desc:     "gigabytes lowercase",
+			value:    "2g",
+			wantSize: 2 * 1024 * 1024 * 1024,
+		},
+		{
+			desc:     "unlimited",
+			value:    "-1",
+			wantSize: -1,
+		},
+		{
+			desc:     "missing",
+			value:    nil,
+			wantSize: 0,
+		},
+		{
+			desc:    "invalid string",
+			value:   "lots",
+			wantErr: errors.New(`invalid size "lots": strconv.ParseInt: parsing "lots": invalid syntax`),
+		},
+		{
+			desc:    "wrong type",
+			value:   true,
+			wantErr: errors.New("unexpected type for size: bool"),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := parseByteSize(tc.value)
+
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if size != tc.wantSize {
+				t.Errorf("got size %d, want %d", size, tc.wantSize)
+			}
+		})
+	}
+}
+
+func TestParsePHPFPM(t *testing.T) {
+	tt := []struct {
+		desc    string
+		input   string
+		wantFPM *FPM
+	}{
+		{
+			desc:    "missing",
+			input:   `{"version": "7.4.0"}`,
+			wantFPM: nil,
+		},
+		{
+			desc:    "not running fpm",
+			input:   `{"version": "7.4.0", "fpm": false}`,
+			wantFPM: nil,
+		},
+		{
+			desc:  "fpm status",
+			input: `{"version": "7.4.0", "fpm": {"pool": "www", "idle-processes": 3, "active-processes": 2, "total-processes": 5}}`,
+			wantFPM: &FPM{
+				Pool:            "www",
+				IdleProcesses:   3,
+				ActiveProcesses: 2,
+				TotalProcesses:  5,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var php PHP
+			if err := json.Unmarshal([]byte(tc.input), &php); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(php.FPM, tc.wantFPM); diff != "" {
+				t.Errorf("fpm differs: -got +want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParsePHPOPcache(t *testing.T) {
+	tt := []struct {
+		desc        string
+		input       string
+		wantOPcache *OPcache
+	}{
+		{
+			desc:        "missing",
+			input:       `{"version": "7.4.0"}`,
+			wantOPcache: nil,
+		},
+		{
+			desc:        "extension not loaded",
+			input:       `{"version": "7.4.0", "opcache": false}`,
+			wantOPcache: nil,
+		},
+		{
+			desc:  "opcache status",
+			input: `{"version": "7.4.0", "opcache": {"opcache_enabled": true, "opcache_statistics": {"hits": 90, "misses": 10, "oom_restarts": 2, "hash_restarts": 1, "manual_restarts": 3}}}`,
+			wantOPcache: &OPcache{
+				Enabled: true,
+				Statistics: OPcacheStatistics{
+					Hits:           90,
+					Misses:         10,
+					OOMRestarts:    2,
+					HashRestarts:   1,
+					ManualRestarts: 3,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var php PHP
+			if err := json.Unmarshal([]byte(tc.input), &php); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(php.OPcache, tc.wantOPcache); diff != "" {
+				t.Errorf("opcache differs: -got +want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParsePHPSettings(t *testing.T) {
+	input := `{"version": "8.1.2", "memory_limit": "512M", "max_execution_time": 3600, "output_buffering": false, "opcache": {"opcache_enabled": true}, "extensions": ["core", "date"]}`
+	wantSettings := map[string]interface{}{
+		"version":            "8.1.2",
+		"memory_limit":       "512M",
+		"max_execution_time": float64(3600),
+		"output_buffering":   false,
+	}
+
+	var php PHP
+	if err := json.Unmarshal([]byte(input), &php); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if diff := cmp.Diff(php.Settings, wantSettings); diff != "" {
+		t.Errorf("settings differ: -got +want\n%s", diff)
+	}
+}
+
+func TestParsePHPAPCu(t *testing.T) {
+	tt := []struct {
+		desc     string
+		input    string
+		wantAPCu *APCu
+	}{
+		{
+			desc:     "missing",
+			input:    `{"version": "7.4.0"}`,
+			wantAPCu: nil,
+		},
+		{
+			desc:     "extension not loaded",
+			input:    `{"version": "7.4.0", "apcu": false}`,
+			wantAPCu: nil,
+		},
+		{
+			desc:  "apcu status",
+			input: `{"version": "7.4.0", "apcu": {"cache": {"num_hits": 175992, "num_misses": 1948}}}`,
+			wantAPCu: &APCu{
+				Cache: APCuCache{
+					Hits:   175992,
+					Misses: 1948,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var php PHP
+			if err := json.Unmarshal([]byte(tc.input), &php); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(php.APCu, tc.wantAPCu); diff != "" {
+				t.Errorf("apcu differs: -got +want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSections(t *testing.T) {
+	tt := []struct {
+		desc        string
+		input       string
+		wantPresent map[string]bool
+	}{
+		{
+			desc:  "all sections",
+			input: `{"ocs": {"data": {"nextcloud": {"system": {}, "storage": {}, "shares": {}}, "server": {"php": {}, "database": {"size": 0}}, "activeUsers": {}}}}`,
+			wantPresent: map[string]bool{
+				SectionSystem:      true,
+				SectionStorage:     true,
+				SectionShares:      true,
+				SectionPHP:         true,
+				SectionDatabase:    true,
+				SectionActiveUsers: true,
+			},
+		},
+		{
+			desc:  "missing sections",
+			input: `{"ocs": {"data": {"nextcloud": {"system": {}, "storage": {}}, "server": {"php": {}}}}}`,
+			wantPresent: map[string]bool{
+				SectionSystem:      true,
+				SectionStorage:     true,
+				SectionShares:      false,
+				SectionPHP:         true,
+				SectionDatabase:    false,
+				SectionActiveUsers: false,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := ParseJSON(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(info.Data.Present, tc.wantPresent); diff != "" {
+				t.Errorf("present sections differ: -got +want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSharePermissions(t *testing.T) {
+	tt := []struct {
+		desc            string
+		input           string
+		wantPermissions map[SharePermissions]uint
+	}{
+		{
+			desc:            "no permissions",
+			input:           `{"ocs": {"data": {"nextcloud": {"shares": {"num_shares": 1}}, "server": {"database": {"size": 0}}}}}`,
+			wantPermissions: nil,
+		},
+		{
+			desc:  "string and number values",
+			input: `{"ocs": {"data": {"nextcloud": {"shares": {"num_shares": 6, "permissions_3_1": "2", "permissions_3_4": 3, "permissions_0_31": "1"}}, "server": {"database": {"size": 0}}}}}`,
+			wantPermissions: map[SharePermissions]uint{
+				{ShareType: 3, Permissions: 1}:  2,
+				{ShareType: 3, Permissions: 4}:  3,
+				{ShareType: 0, Permissions: 31}: 1,
+			},
+		},
+		{
+			desc:  "invalid entries",
+			input: `{"ocs": {"data": {"nextcloud": {"shares": {"permissions_3_4": "1", "permissions_3": "1", "permissions_x_1": "1", "permissions_3_1": "n/a"}}, "server": {"database": {"size": 0}}}}}`,
+			wantPermissions: map[SharePermissions]uint{
+				{ShareType: 3, Permissions: 4}: 1,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := ParseJSON(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(info.Data.Nextcloud.Shares.Permissions, tc.wantPermissions); diff != "" {
+				t.Errorf("permissions differ: %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseOCSStatus(t *testing.T) {
+	tt := []struct {
+		desc           string
+		input          string
+		wantErr        error
+		wantStatusCode int
+	}{
+		{
+			desc:           "ok",
+			input:          `{"ocs": {"meta": {"status": "ok", "statuscode": 200, "message": "OK"}, "data": {"server": {"database": {"size": 0}}}}}`,
+			wantStatusCode: 200,
+		},
+		{
+			desc:    "failure",
+			input:   `{"ocs": {"meta": {"status": "failure", "statuscode": 998, "message": ""}, "data": []}}`,
+			wantErr: errors.New("server returned OCS status code 998"),
+		},
+		{
+			desc:    "failure with message",
+			input:   `{"ocs": {"meta": {"status": "failure", "statuscode": 997, "message": "Current user is not logged in"}, "data": []}}`,
+			wantErr: errors.New("server returned OCS status code 997: Current user is not logged in"),
+		},
+		{
+			desc:  "no meta",
+			input: `{"ocs": {"data": {"server": {"database": {"size": 0}}}}}`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := ParseJSON(strings.NewReader(tc.input))
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if err != nil {
+				var ocsErr *OCSError
+				if !errors.As(err, &ocsErr) {
+					t.Errorf("got error of type %T, want *OCSError", err)
+				}
+				return
+			}
+
+			if info.Meta.StatusCode != tc.wantStatusCode {
+				t.Errorf("got status code %d, want %d", info.Meta.StatusCode, tc.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestParseActiveUsers(t *testing.T) {
+	tt := []struct {
+		desc            string
+		input           string
+		wantActiveUsers ActiveUsers
+	}{
+		{
+			desc:  "no periods",
+			input: `{"last5minutes": 1, "last1hour": 2, "last24hours": 3}`,
+			wantActiveUsers: ActiveUsers{
+				Last5Minutes: 1,
+				LastHour:     2,
+				LastDay:      3,
+			},
+		},
+		{
+			desc:  "all periods",
+			input: `{"last5minutes": 1, "last1hour": 2, "last24hours": 3, "last7days": 4, "last1month": 5, "last3months": 6, "last6months": 7, "lastyear": 8}`,
+			wantActiveUsers: ActiveUsers{
+				Last5Minutes: 1,
+				LastHour:     2,
+				LastDay:      3,
+				Periods: map[string]uint{
+					"last7days":   4,
+					"last1month":  5,
+					"last3months": 6,
+					"last6months": 7,
+					"lastyear":    8,
+				},
+			},
+		},
+		{
+			desc:  "some periods as strings",
+			input: `{"last5minutes": 1, "last1hour": 2, "last24hours": 3, "last7days": "4", "lastyear": 8, "unknown": 9}`,
+			wantActiveUsers: ActiveUsers{
+				Last5Minutes: 1,
+				LastHour:     2,
+				LastDay:      3,
+				Periods: map[string]uint{
+					"last7days": 4,
+					"lastyear":  8,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var activeUsers ActiveUsers
+			if err := json.Unmarshal([]byte(tc.input), &activeUsers); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if diff := cmp.Diff(activeUsers, tc.wantActiveUsers); diff != "" {
+				t.Errorf("active users differ: -got +want\n%s", diff)
+			}
+		})
+	}
+}
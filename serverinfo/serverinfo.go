@@ -5,13 +5,21 @@ import (
 	"encoding/xml"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 const (
-	// InfoPath contains the path to the serverinfo endpoint.
-	InfoPath = "/ocs/v2.php/apps/serverinfo/api/v1/info?format=json"
+	// DefaultAPIVersion is the version of the serverinfo API used if no other version is specified.
+	DefaultAPIVersion = "v1"
+
+	infoPathFormat = "/ocs/v2.php/apps/serverinfo/api/%s/info?format=json"
 )
 
+// InfoPath returns the path to the serverinfo endpoint for the provided API version.
+func InfoPath(apiVersion string) string {
+	return fmt.Sprintf(infoPathFormat, apiVersion)
+}
+
 // ServerInfo contains the complete data received from the server.
 type ServerInfo struct {
 	Meta Meta `json:"meta"`
@@ -25,11 +33,61 @@ type Meta struct {
 	Message    string `json:"message"`
 }
 
+// Names of the sections of the server info.
+const (
+	SectionSystem      = "system"
+	SectionStorage     = "storage"
+	SectionShares      = "shares"
+	SectionPHP         = "php"
+	SectionDatabase    = "database"
+	SectionActiveUsers = "active_users"
+)
+
+// Sections contains the names of all sections of the server info.
+var Sections = []string{
+	SectionSystem,
+	SectionStorage,
+	SectionShares,
+	SectionPHP,
+	SectionDatabase,
+	SectionActiveUsers,
+}
+
 // Data contains the status information about the instance.
 type Data struct {
 	Nextcloud   Nextcloud   `json:"nextcloud"`
 	Server      Server      `json:"server"`
 	ActiveUsers ActiveUsers `json:"activeUsers"`
+	// Present contains the names of the sections which were contained in the server info.
+	Present map[string]bool `json:"-"`
+}
+
+func (d *Data) UnmarshalJSON(data []byte) error {
+	type plainData Data
+	var plain plainData
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+
+	var raw struct {
+		Nextcloud   map[string]json.RawMessage `json:"nextcloud"`
+		Server      map[string]json.RawMessage `json:"server"`
+		ActiveUsers json.RawMessage            `json:"activeUsers"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*d = Data(plain)
+	d.Present = map[string]bool{
+		SectionSystem:      isPresent(raw.Nextcloud["system"]),
+		SectionStorage:     isPresent(raw.Nextcloud["storage"]),
+		SectionShares:      isPresent(raw.Nextcloud["shares"]),
+		SectionPHP:         isPresent(raw.Server["php"]),
+		SectionDatabase:    isPresent(raw.Server["database"]),
+		SectionActiveUsers: isPresent(raw.ActiveUsers),
+	}
+	return nil
 }
 
 // Nextcloud contains information about the nextcloud installation.
@@ -141,14 +199,104 @@ type Shares struct {
 	SharesGroups         uint `json:"num_shares_groups"`
 	SharesLink           uint `json:"num_shares_link"`
 	SharesLinkNoPassword uint `json:"num_shares_link_no_password"`
+	SharesRoom           uint `json:"num_shares_room"`
 	FedSent              uint `json:"num_fed_shares_sent"`
 	FedReceived          uint `json:"num_fed_shares_received"`
-	// <permissions_0_1>2</permissions_0_1>
-	// <permissions_3_1>4</permissions_3_1>
-	// <permissions_0_15>2</permissions_0_15>
-	// <permissions_3_15>2</permissions_3_15>
-	// <permissions_0_31>3</permissions_0_31>
-	// <permissions_1_31>1</permissions_1_31>
+	// Permissions contains the number of shares by share type and permissions. It is read from the
+	// "permissions_<type>_<permissions>" keys, for example "permissions_3_1".
+	Permissions map[SharePermissions]uint `json:"-"`
+}
+
+// SharePermissions identifies a group of shares by their share type and permissions.
+type SharePermissions struct {
+	ShareType   uint
+	Permissions uint
+}
+
+const (
+	// ShareTypeLink is the share type of public links.
+	ShareTypeLink = 3
+	// PermissionCreate allows creating files. A public link with only this permission is a "file drop".
+	PermissionCreate = 4
+
+	permissionsPrefix = "permissions_"
+)
+
+func (s *Shares) UnmarshalJSON(data []byte) error {
+	type plainShares Shares
+	var plain plainShares
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = Shares(plain)
+	for key, value := range raw {
+		if !strings.HasPrefix(key, permissionsPrefix) {
+			continue
+		}
+
+		permissions, ok := parseSharePermissions(strings.TrimPrefix(key, permissionsPrefix))
+		if !ok {
+			continue
+		}
+
+		count, ok := parseCount(value)
+		if !ok {
+			continue
+		}
+
+		if s.Permissions == nil {
+			s.Permissions = make(map[SharePermissions]uint)
+		}
+		s.Permissions[permissions] = count
+	}
+	return nil
+}
+
+func parseSharePermissions(key string) (SharePermissions, bool) {
+	tokens := strings.Split(key, "_")
+	if len(tokens) != 2 {
+		return SharePermissions{}, false
+	}
+
+	shareType, err := strconv.ParseUint(tokens[0], 10, 32)
+	if err != nil {
+		return SharePermissions{}, false
+	}
+
+	permissions, err := strconv.ParseUint(tokens[1], 10, 32)
+	if err != nil {
+		return SharePermissions{}, false
+	}
+
+	return SharePermissions{
+		ShareType:   uint(shareType),
+		Permissions: uint(permissions),
+	}, true
+}
+
+// parseCount parses a count, which is either encoded as a number or as a string.
+func parseCount(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint(v), true
+	case string:
+		count, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0, false
+		}
+		return uint(count), true
+	default:
+		return 0, false
+	}
 }
 
 // Server contains information about the servers running nextcloud.
@@ -160,10 +308,173 @@ type Server struct {
 
 // PHP contains information about the PHP installation.
 type PHP struct {
-	Version           string `json:"version"`
-	MemoryLimit       int64  `json:"memory_limit"`
-	MaxExecutionTime  uint   `json:"max_execution_time"`
-	UploadMaxFilesize int64  `json:"upload_max_filesize"`
+	Version           string   `json:"version"`
+	MemoryLimit       int64    `json:"memory_limit"`
+	MaxExecutionTime  uint     `json:"max_execution_time"`
+	UploadMaxFilesize int64    `json:"upload_max_filesize"`
+	FPM               *FPM     `json:"fpm"`
+	OPcache           *OPcache `json:"opcache"`
+	APCu              *APCu    `json:"apcu"`
+	// Settings contains all values of the section which are a number, string or boolean by their key.
+	Settings map[string]interface{} `json:"-"`
+}
+
+func (p *PHP) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version           string          `json:"version"`
+		MemoryLimit       interface{}     `json:"memory_limit"`
+		MaxExecutionTime  uint            `json:"max_execution_time"`
+		UploadMaxFilesize interface{}     `json:"upload_max_filesize"`
+		FPM               json.RawMessage `json:"fpm"`
+		OPcache           json.RawMessage `json:"opcache"`
+		APCu              json.RawMessage `json:"apcu"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	settings := make(map[string]interface{})
+	for key, value := range values {
+		switch value.(type) {
+		case float64, string, bool:
+			settings[key] = value
+		}
+	}
+
+	// fpm is "false" when PHP is not running using FPM
+	var fpm *FPM
+	if isPresent(raw.FPM) {
+		fpm = &FPM{}
+		if err := json.Unmarshal(raw.FPM, fpm); err != nil {
+			return fmt.Errorf("can not parse php.fpm: %w", err)
+		}
+	}
+
+	// opcache is "false" when the extension is not loaded
+	var opcache *OPcache
+	if isPresent(raw.OPcache) {
+		opcache = &OPcache{}
+		if err := json.Unmarshal(raw.OPcache, opcache); err != nil {
+			return fmt.Errorf("can not parse php.opcache: %w", err)
+		}
+	}
+
+	// apcu is "false" when the extension is not loaded
+	var apcu *APCu
+	if isPresent(raw.APCu) {
+		apcu = &APCu{}
+		if err := json.Unmarshal(raw.APCu, apcu); err != nil {
+			return fmt.Errorf("can not parse php.apcu: %w", err)
+		}
+	}
+
+	memoryLimit, err := parseByteSize(raw.MemoryLimit)
+	if err != nil {
+		return fmt.Errorf("can not parse php.memory_limit: %w", err)
+	}
+
+	uploadMaxFilesize, err := parseByteSize(raw.UploadMaxFilesize)
+	if err != nil {
+		return fmt.Errorf("can not parse php.upload_max_filesize: %w", err)
+	}
+
+	p.Version = raw.Version
+	p.MemoryLimit = memoryLimit
+	p.MaxExecutionTime = raw.MaxExecutionTime
+	p.UploadMaxFilesize = uploadMaxFilesize
+	p.FPM = fpm
+	p.OPcache = opcache
+	p.APCu = apcu
+	p.Settings = settings
+	return nil
+}
+
+// isPresent returns false, if an optional section is missing or was reported as "false".
+func isPresent(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "false" && string(raw) != "null"
+}
+
+// FPM contains status information about the PHP-FPM pool serving Nextcloud.
+type FPM struct {
+	Pool               string `json:"pool"`
+	ProcessManager     string `json:"process-manager"`
+	AcceptedConn       uint   `json:"accepted-conn"`
+	ListenQueue        uint   `json:"listen-queue"`
+	IdleProcesses      uint   `json:"idle-processes"`
+	ActiveProcesses    uint   `json:"active-processes"`
+	TotalProcesses     uint   `json:"total-processes"`
+	MaxActiveProcesses uint   `json:"max-active-processes"`
+	MaxChildrenReached uint   `json:"max-children-reached"`
+	SlowRequests       uint   `json:"slow-requests"`
+}
+
+// OPcache contains status information about the PHP opcode cache.
+type OPcache struct {
+	Enabled    bool              `json:"opcache_enabled"`
+	Statistics OPcacheStatistics `json:"opcache_statistics"`
+}
+
+// OPcacheStatistics contains the usage statistics of the opcode cache.
+type OPcacheStatistics struct {
+	Hits           uint64 `json:"hits"`
+	Misses         uint64 `json:"misses"`
+	OOMRestarts    uint   `json:"oom_restarts"`
+	HashRestarts   uint   `json:"hash_restarts"`
+	ManualRestarts uint   `json:"manual_restarts"`
+}
+
+// APCu contains status information about the APCu user cache.
+type APCu struct {
+	Cache APCuCache `json:"cache"`
+}
+
+// APCuCache contains the usage statistics of the APCu cache.
+type APCuCache struct {
+	Hits   uint64 `json:"num_hits"`
+	Misses uint64 `json:"num_misses"`
+}
+
+// parseByteSize converts a value into a number of bytes. Strings can use the PHP shorthand notation ("512M", "2G").
+func parseByteSize(value interface{}) (int64, error) {
+	switch raw := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int64(raw), nil
+	case string:
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return 0, nil
+		}
+
+		multiplier := int64(1)
+		switch raw[len(raw)-1] {
+		case 'k', 'K':
+			multiplier = 1 << 10
+		case 'm', 'M':
+			multiplier = 1 << 20
+		case 'g', 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			raw = raw[:len(raw)-1]
+		}
+
+		size, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q: %w", value, err)
+		}
+
+		return size * multiplier, nil
+	default:
+		return 0, fmt.Errorf("unexpected type for size: %T", raw)
+	}
 }
 
 // Database contains information about the database used by nextcloud.
@@ -211,4 +522,49 @@ type ActiveUsers struct {
 	Last5Minutes uint `json:"last5minutes"`
 	LastHour     uint `json:"last1hour"`
 	LastDay      uint `json:"last24hours"`
+	// Periods contains the number of active users for the periods longer than a day by their key, for example
+	// "last7days". They are only reported by newer versions of the serverinfo app, so only the periods contained
+	// in the response are present.
+	Periods map[string]uint `json:"-"`
+}
+
+// ActiveUsersPeriods contains the keys of the periods longer than a day reported by newer versions of serverinfo.
+var ActiveUsersPeriods = []string{
+	"last7days",
+	"last1month",
+	"last3months",
+	"last6months",
+	"lastyear",
+}
+
+func (a *ActiveUsers) UnmarshalJSON(data []byte) error {
+	type plainActiveUsers ActiveUsers
+	var plain plainActiveUsers
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*a = ActiveUsers(plain)
+	for _, period := range ActiveUsersPeriods {
+		value, ok := raw[period]
+		if !ok {
+			continue
+		}
+
+		count, ok := parseCount(value)
+		if !ok {
+			continue
+		}
+
+		if a.Periods == nil {
+			a.Periods = make(map[string]uint)
+		}
+		a.Periods[period] = count
+	}
+	return nil
 }
@@ -0,0 +1,51 @@
+package serverinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed Nextcloud version like "27.1.4.2".
+type Version []int
+
+// ParseVersion parses a version consisting of up to four numeric parts separated by dots.
+func ParseVersion(version string) (Version, error) {
+	tokens := strings.Split(strings.TrimSpace(version), ".")
+	if len(tokens) > 4 {
+		return nil, fmt.Errorf("version %q has more than four parts", version)
+	}
+
+	result := make(Version, 0, len(tokens))
+	for _, token := range tokens {
+		part, err := strconv.Atoi(token)
+		if err != nil || part < 0 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+
+		result = append(result, part)
+	}
+
+	return result, nil
+}
+
+// Less returns true if v is an older version than other. Missing parts are treated as zero, so "27" and "27.0.0.0"
+// are the same version.
+func (v Version) Less(other Version) bool {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		a, b := v.part(i), other.part(i)
+		if a != b {
+			return a < b
+		}
+	}
+
+	return false
+}
+
+func (v Version) part(i int) int {
+	if i >= len(v) {
+		return 0
+	}
+
+	return v[i]
+}
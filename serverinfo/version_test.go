@@ -0,0 +1,88 @@
+package serverinfo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xperimental/nextcloud-exporter/internal/testutil"
+)
+
+func TestParseVersion(t *testing.T) {
+	tt := []struct {
+		version     string
+		wantVersion Version
+		wantErr     error
+	}{
+		{
+			version:     "27.1.4.2",
+			wantVersion: Version{27, 1, 4, 2},
+		},
+		{
+			version:     "27",
+			wantVersion: Version{27},
+		},
+		{
+			version: "",
+			wantErr: errors.New(`invalid version ""`),
+		},
+		{
+			version: "27.1.x",
+			wantErr: errors.New(`invalid version "27.1.x"`),
+		},
+		{
+			version: "1.2.3.4.5",
+			wantErr: errors.New(`version "1.2.3.4.5" has more than four parts`),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.version, func(t *testing.T) {
+			t.Parallel()
+
+			version, err := ParseVersion(tc.version)
+			if !testutil.EqualErrorMessage(err, tc.wantErr) {
+				t.Errorf("got error %q, want %q", err, tc.wantErr)
+			}
+
+			if diff := cmp.Diff(version, tc.wantVersion); diff != "" {
+				t.Errorf("version differs: %s", diff)
+			}
+		})
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	tt := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "26.0.8", b: "27", want: true},
+		{a: "27.0.0.0", b: "27", want: false},
+		{a: "27", b: "27.0.0.1", want: true},
+		{a: "27.1.4.2", b: "27.1.4.10", want: true},
+		{a: "28.0.1", b: "27.1.4.2", want: false},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.a+"<"+tc.b, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := ParseVersion(tc.a)
+			if err != nil {
+				t.Fatalf("error parsing %q: %s", tc.a, err)
+			}
+
+			b, err := ParseVersion(tc.b)
+			if err != nil {
+				t.Fatalf("error parsing %q: %s", tc.b, err)
+			}
+
+			if got := a.Less(b); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}